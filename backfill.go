@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+
+	. "scrape/common"
+	"scrape/dhs"
+	"scrape/scraper"
+)
+
+// backfillDates returns every date from..to that is absent from at least one
+// of the datasets, or only present as a missing placeholder in histories.
+func backfillDates(from, to string, histories Histories, reports TestReports, hotspots HotspotsHistories) ([]string, error) {
+	h, r, s := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+	for _, v := range histories.History {
+		if !v.Missing {
+			h[v.Date] = true
+		}
+	}
+	for _, v := range reports.Reports {
+		r[v.Date] = true
+	}
+	for _, v := range hotspots.History {
+		s[v.Date] = true
+	}
+	start, err := ParseDate(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ParseDate(to)
+	if err != nil {
+		return nil, err
+	}
+	var dates []string
+	for t := start; !t.After(end); t = t.AddDate(0, 0, 1) {
+		d := FormatDate(t)
+		if !h[d] || !r[d] || !s[d] {
+			dates = append(dates, d)
+		}
+	}
+	return dates, nil
+}
+
+// parseBulletin parses the bulletin of the date, turning a panic while
+// parsing a malformed PDF into an error.
+func parseBulletin(date string) (b dhs.Bulletin, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parsing panicked: %v", r)
+		}
+	}()
+	return dhs.ParseBulletin(date)
+}
+
+// fetchBulletins parses the bulletin of every date with at most workers
+// downloads in flight, skipping dates whose bulletin can't be parsed.
+func fetchBulletins(dates []string, workers int) map[string]dhs.Bulletin {
+	jobs := make(chan string)
+	results := make(map[string]dhs.Bulletin)
+	var mu sync.Mutex
+	var pool sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		pool.Add(1)
+		go func() {
+			defer pool.Done()
+			for d := range jobs {
+				b, err := parseBulletin(d)
+				if err != nil {
+					log.Printf("ERROR parsing bulletin for %v: %v\n", d, err)
+					continue
+				}
+				mu.Lock()
+				results[d] = b
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, d := range dates {
+		jobs <- d
+	}
+	close(jobs)
+	pool.Wait()
+	return results
+}
+
+func backfillCommand(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := fs.String("from", "", "first date to backfill (dd-mm-yyyy)")
+	to := fs.String("to", "", "last date to backfill (dd-mm-yyyy)")
+	workers := fs.Int("workers", 4, "number of bulletins downloaded concurrently")
+	fs.Parse(args)
+	if *from == "" || *to == "" || *workers < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var histories Histories
+	var reports TestReports
+	var hotspots HotspotsHistories
+	ReadJSON(HISTORIES_FILE, &histories)
+	ReadJSON(TEST_REPORTS_FILE, &reports)
+	ReadJSON(HOTSPOT_HISTORIES_FILE, &hotspots)
+	dates, err := backfillDates(*from, *to, histories, reports, hotspots)
+	if err != nil {
+		log.Panicln("ERROR reading backfill range", err)
+	}
+	log.Printf("backfilling %v dates\n", len(dates))
+	bulletins := fetchBulletins(dates, *workers)
+
+	hindex := make(map[string]int)
+	for i, v := range histories.History {
+		hindex[v.Date] = i
+	}
+	rindex := make(map[string]bool)
+	for _, v := range reports.Reports {
+		rindex[v.Date] = true
+	}
+	sindex := make(map[string]bool)
+	for _, v := range hotspots.History {
+		sindex[v.Date] = true
+	}
+	var nh, nr, ns int
+	for _, d := range dates {
+		b, ok := bulletins[d]
+		if !ok {
+			continue
+		}
+		if b.Summary != nil {
+			h := scraper.History{Summary: b.Summary, Date: d, Source: dhs.BULLETIN_SOURCE}
+			if i, ok := hindex[d]; ok {
+				if histories.History[i].Missing {
+					histories.History[i] = h
+					nh++
+				}
+			} else {
+				histories.History = append(histories.History, h)
+				nh++
+			}
+		}
+		if b.TestReport != nil && !rindex[d] {
+			reports.Reports = append(reports.Reports, *b.TestReport)
+			nr++
+		}
+		if len(b.Hotspots.Hotspots) > 0 && !sindex[d] {
+			hotspots.History = append(hotspots.History, b.Hotspots.ToHistory())
+			ns++
+		}
+	}
+	if nh > 0 {
+		histories.History = repairHistories(histories.History)
+		WriteJSON(histories, HISTORIES_FILE)
+	}
+	if nr > 0 {
+		sort.SliceStable(reports.Reports, func(i, j int) bool {
+			return DateBefore(reports.Reports[i].Date, reports.Reports[j].Date)
+		})
+		WriteJSON(reports, TEST_REPORTS_FILE)
+	}
+	if ns > 0 {
+		sort.SliceStable(hotspots.History, func(i, j int) bool {
+			return DateBefore(hotspots.History[i].Date, hotspots.History[j].Date)
+		})
+		WriteJSON(hotspots, HOTSPOT_HISTORIES_FILE)
+	}
+	if err := Reviews.Flush(REVIEW_QUEUE_FILE); err != nil {
+		log.Println("ERROR writing review queue", err)
+	}
+	log.Printf("backfilled %v histories, %v test reports and %v hotspot histories\n", nh, nr, ns)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+
+	. "scrape/common"
+	"scrape/dhs"
+)
+
+func bulletinsUsage() {
+	fmt.Fprintln(os.Stderr, `usage: scrape bulletins <command> [arguments]
+
+  list                              list the bulletins held in the library
+  fetch -from date -to date [-force] download the bulletins of a date range
+  show [-lang en|ml] date           print the text of a date's bulletins
+  search [-from date -to date] regex print every line matching regex`)
+	os.Exit(2)
+}
+
+// inRange reports whether date lies within from..to, an empty bound being
+// open.
+func inRange(date string, from string, to string) bool {
+	return (from == "" || !DateBefore(date, from)) && (to == "" || !DateBefore(to, date))
+}
+
+func bulletinsCommand(args []string) {
+	if len(args) < 1 {
+		bulletinsUsage()
+	}
+	lib := dhs.DefaultLibrary
+	switch args[0] {
+	case "list":
+		entries, err := lib.Entries()
+		if err != nil {
+			log.Panicln("ERROR reading bulletin library", err)
+		}
+		for _, e := range entries {
+			fmt.Printf("%v\t%v\t%v\t%v\t%v\n", e.Date, e.Attachment.Language, e.Attachment.Kind, e.File, e.Post.Title)
+		}
+	case "fetch":
+		fs := flag.NewFlagSet("bulletins fetch", flag.ExitOnError)
+		from := fs.String("from", "", "first date to fetch (dd-mm-yyyy)")
+		to := fs.String("to", "", "last date to fetch (dd-mm-yyyy)")
+		force := fs.Bool("force", false, "download dates already in the library again")
+		fs.Parse(args[1:])
+		if *from == "" {
+			bulletinsUsage()
+		}
+		if *to == "" {
+			*to = *from
+		}
+		start, err := ParseDate(*from)
+		if err != nil {
+			log.Panicln("ERROR reading fetch range", err)
+		}
+		end, err := ParseDate(*to)
+		if err != nil {
+			log.Panicln("ERROR reading fetch range", err)
+		}
+		for t := start; !t.After(end); t = t.AddDate(0, 0, 1) {
+			entries, err := lib.Fetch(FormatDate(t), *force)
+			if err != nil {
+				log.Printf("ERROR fetching bulletins for %v: %v\n", FormatDate(t), err)
+				continue
+			}
+			log.Printf("%v: %v attachments in library\n", FormatDate(t), len(entries))
+		}
+	case "show":
+		fs := flag.NewFlagSet("bulletins show", flag.ExitOnError)
+		lang := fs.String("lang", dhs.LANG_ENGLISH, "language of the attachments to show")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			bulletinsUsage()
+		}
+		docs, err := lib.Documents(fs.Arg(0), func(a dhs.Attachment) bool { return a.Language == *lang })
+		if err != nil {
+			log.Panicln("ERROR reading bulletin", err)
+		}
+		for _, d := range docs {
+			fmt.Printf("==> %v (%v) <==\n%v\n", d.Attachment.URL, d.Attachment.Kind, d.Text)
+		}
+	case "search":
+		fs := flag.NewFlagSet("bulletins search", flag.ExitOnError)
+		from := fs.String("from", "", "first date to search (dd-mm-yyyy)")
+		to := fs.String("to", "", "last date to search (dd-mm-yyyy)")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			bulletinsUsage()
+		}
+		re, err := regexp.Compile(fs.Arg(0))
+		if err != nil {
+			log.Panicln("ERROR compiling search pattern", err)
+		}
+		hits, err := lib.Search(re)
+		if err != nil {
+			log.Panicln("ERROR searching bulletin library", err)
+		}
+		for _, h := range hits {
+			if inRange(h.Entry.Date, *from, *to) {
+				fmt.Printf("%v:%v:%v: %v\n", h.Entry.Date, h.Entry.TextFile, h.Line, h.Text)
+			}
+		}
+	default:
+		bulletinsUsage()
+	}
+}
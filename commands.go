@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var commands = map[string]func(args []string){
+	"verify":    verifyCommand,
+	"backfill":  backfillCommand,
+	"bulletins": bulletinsCommand,
+	"hotspot":   hotspotCommand,
+	"review":    reviewCommand,
+	"gazetteer": gazetteerCommand,
+	"serve":     serveCommand,
+}
+
+func runCommand(name string, args []string) {
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		os.Exit(2)
+	}
+	cmd(args)
+}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// LSGAliases maps, per district, LSG names used by the sources that fuzzy
+// matching gets wrong to their name in GeoLSG. Aliases accepted from the
+// review queue are kept in a file and loaded over these.
+var LSGAliases = map[string]map[string]string{
+	"Kannur": {
+		"Koothuparamba (M)": "Kuthuparambu (M)",
+		"Mattanur (M)":      "Mattannoor (M)",
+		"Maloor":            "Malur",
+	},
+	"Kottayam": {
+		"Changanacherry (M)": "Changanassery (M)",
+	},
+	"Palakkad": {
+		"District Hospital": "Marutharoad",
+	},
+	"Thiruvananthapuram": {
+		"Neduveli": "Vembayam",
+	},
+}
+
+// DistrictAliases maps the codes and old or colloquial names of the
+// districts used by the sources to their names in DistrictList. The codes
+// of DistrictMap are aliases too.
+var DistrictAliases = map[string]string{
+	"Trivandrum": "Thiruvananthapuram",
+	"TVPM":       "Thiruvananthapuram",
+	"Quilon":     "Kollam",
+	"PTM":        "Pathanamthitta",
+	"Alleppey":   "Alappuzha",
+	"Cochin":     "Ernakulam",
+	"Kochi":      "Ernakulam",
+	"Trichur":    "Thrissur",
+	"TCR":        "Thrissur",
+	"Palghat":    "Palakkad",
+	"MLP":        "Malappuram",
+	"Calicut":    "Kozhikode",
+	"KZD":        "Kozhikode",
+	"Wynad":      "Wayanad",
+	"Cannanore":  "Kannur",
+	"Kasargod":   "Kasaragod",
+	"KSD":        "Kasaragod",
+}
+
+// ResolveDistrictAlias returns the district a code or alias stands for.
+func ResolveDistrictAlias(name string) (string, bool) {
+	key := aliasKey(name)
+	for code, d := range DistrictMap {
+		if aliasKey(code) == key {
+			return d, true
+		}
+	}
+	for alias, d := range DistrictAliases {
+		if aliasKey(alias) == key {
+			return d, true
+		}
+	}
+	return name, false
+}
+
+// aliasKey normalises a name for alias lookups, ignoring case and spacing.
+func aliasKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// ResolveLSGAlias returns the GeoLSG name of an alias in the district, or
+// the name unchanged when it isn't an alias.
+func ResolveLSGAlias(district string, name string) (string, bool) {
+	key := aliasKey(name)
+	for alias, lsg := range LSGAliases[district] {
+		if aliasKey(alias) == key {
+			return lsg, true
+		}
+	}
+	return name, false
+}
+
+// MatchLSG resolves an LSG name of the district, first through the alias
+// table and then by fuzzy matching, against the LSGs valid on the date, the
+// current ones when it is empty. An alias of an LSG the gazetteer knows but
+// not on the date is passed on to fuzzy matching.
+func MatchLSG(district string, name string, date string) *Match {
+	if lsg, ok := ResolveLSGAlias(district, name); ok {
+		if l, known := LSGGazetteer.LookupAt(district, lsg, date); !known || l.ValidOn(date) {
+			return &Match{Match: lsg, Score: 100, Query: Normalize(name), Key: Normalize(lsg), Via: name}
+		}
+	}
+	if top := LSGGazetteer.Match(district, name, date, 1); len(top) > 0 {
+		return &top[0]
+	}
+	return &Match{Query: Normalize(name)}
+}
+
+// AddLSGAlias maps the alias to the LSG in the district.
+func AddLSGAlias(district string, alias string, lsg string) {
+	if LSGAliases[district] == nil {
+		LSGAliases[district] = make(map[string]string)
+	}
+	LSGAliases[district][alias] = lsg
+}
+
+// LoadLSGAliases adds the aliases of the file, if it exists, to the table.
+func LoadLSGAliases(filename string) error {
+	s, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var aliases map[string]map[string]string
+	if err := json.Unmarshal(s, &aliases); err != nil {
+		return err
+	}
+	for d, m := range aliases {
+		for alias, lsg := range m {
+			AddLSGAlias(d, alias, lsg)
+		}
+	}
+	return nil
+}
+
+// SaveLSGAlias adds an alias to the file, creating it if needed.
+func SaveLSGAlias(filename string, district string, alias string, lsg string) error {
+	aliases := make(map[string]map[string]string)
+	s, err := ioutil.ReadFile(filename)
+	if err == nil {
+		if err := json.Unmarshal(s, &aliases); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if aliases[district] == nil {
+		aliases[district] = make(map[string]string)
+	}
+	aliases[district][alias] = lsg
+	j, err := json.MarshalIndent(aliases, "", "  ")
+	if err != nil {
+		return err
+	}
+	AddLSGAlias(district, alias, lsg)
+	return ioutil.WriteFile(filename, j, 0644)
+}
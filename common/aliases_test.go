@@ -0,0 +1,29 @@
+package common
+
+import "testing"
+
+func TestMatchLSGAliasDate(t *testing.T) {
+	defer func(g *Gazetteer, a map[string]map[string]string) {
+		LSGGazetteer, LSGAliases = g, a
+	}(LSGGazetteer, LSGAliases)
+	LSGAliases = make(map[string]map[string]string)
+	AddLSGAlias("Kollam", "Kottarakara Town", "Kottarakkara (M)")
+	LSGGazetteer = NewGazetteer()
+	// the municipality was formed in November 2015
+	LSGGazetteer.add(LSG{District: "Kollam", GeoName: "Kottarakkara (M)", From: "01-11-2015"})
+	tests := []struct {
+		date     string
+		resolved bool
+	}{
+		{"", true},
+		{"01-05-2020", true},
+		{"01-11-2015", true},
+		{"01-05-2015", false},
+	}
+	for _, tt := range tests {
+		m := MatchLSG("Kollam", "Kottarakara Town", tt.date)
+		if resolved := m.Match == "Kottarakkara (M)" && m.Score == 100; resolved != tt.resolved {
+			t.Errorf("%q: got %v (%v), want the alias resolved %v", tt.date, m.Match, m.Score, tt.resolved)
+		}
+	}
+}
@@ -34,6 +34,24 @@ var DistrictList = []string{
 	"Kasaragod",
 }
 
+// DistrictMalayalam holds the Malayalam names of the districts.
+var DistrictMalayalam = map[string][]string{
+	"Thiruvananthapuram": {"തിരുവനന്തപുരം"},
+	"Kollam":             {"കൊല്ലം"},
+	"Pathanamthitta":     {"പത്തനംതിട്ട"},
+	"Alappuzha":          {"ആലപ്പുഴ"},
+	"Kottayam":           {"കോട്ടയം"},
+	"Idukki":             {"ഇടുക്കി"},
+	"Ernakulam":          {"എറണാകുളം"},
+	"Thrissur":           {"തൃശ്ശൂർ", "തൃശൂർ"},
+	"Palakkad":           {"പാലക്കാട്"},
+	"Malappuram":         {"മലപ്പുറം"},
+	"Kozhikode":          {"കോഴിക്കോട്"},
+	"Wayanad":            {"വയനാട്"},
+	"Kannur":             {"കണ്ണൂർ"},
+	"Kasaragod":          {"കാസർഗോഡ്", "കാസറഗോഡ്", "കാസർകോട്"},
+}
+
 var GeoLSG = map[string][]string{
 	"Alappuzha": {
 		"Edathwa",
@@ -0,0 +1,343 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	LSG_GRAMA_PANCHAYAT = "grama_panchayat"
+	LSG_MUNICIPALITY    = "municipality"
+	LSG_CORPORATION     = "corporation"
+)
+
+// LSG is a local self government body. ID is always set and stable across
+// runs; Code is the LGD code and the other details are only known when a
+// gazetteer file supplies them. No gazetteer file ships with the scraper,
+// so until one is written with `scrape gazetteer -o` and filled in, records
+// only carry the ID, name and type derived from GeoLSG. From and To bound, inclusively, the dates
+// the record is valid; empty means unbounded. An LSG that was renamed or
+// merged has a record per version, the old ones pointing to their
+// successors in ReplacedBy.
+type LSG struct {
+	ID       string `json:"id"`
+	Code     string `json:"code,omitempty"`
+	District string `json:"district"`
+	// Name is the canonical name without the type suffix, GeoName the name
+	// as listed in GeoLSG.
+	Name       string   `json:"name"`
+	GeoName    string   `json:"geo_name"`
+	Type       string   `json:"type"`
+	Block      string   `json:"block,omitempty"`
+	Taluk      string   `json:"taluk,omitempty"`
+	Wards      int      `json:"wards,omitempty"`
+	AltNames   []string `json:"alt_names,omitempty"`
+	Malayalam  string   `json:"malayalam,omitempty"`
+	From       string   `json:"from,omitempty"`
+	To         string   `json:"to,omitempty"`
+	ReplacedBy []string `json:"replaced_by,omitempty"`
+}
+
+// ValidOn reports whether the record is valid on the date, dd-mm-yyyy. The
+// current records are valid on the empty date.
+func (l LSG) ValidOn(date string) bool {
+	if date == "" {
+		return l.To == ""
+	}
+	if l.From != "" && DateBefore(date, l.From) {
+		return false
+	}
+	return l.To == "" || !DateBefore(l.To, date)
+}
+
+var (
+	reTypeSuffix = regexp.MustCompile(`\s*\(([MC])\)\s*$`)
+	reSlug       = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Gazetteer holds every version of every LSG, indexed by ID and by
+// district and GeoLSG name.
+type Gazetteer struct {
+	LSGs   []LSG
+	byID   map[string]int
+	byName map[string][]int
+	// matchers index the names of each district's LSGs, built on first use.
+	matchers   map[string]*Matcher
+	matchersMu sync.Mutex
+}
+
+// districtCode returns the three letter code of the district.
+func districtCode(district string) string {
+	for code, d := range DistrictMap {
+		if d == district {
+			return code
+		}
+	}
+	return strings.ToUpper(district)
+}
+
+// lsgID derives the stable ID of an LSG from its district and GeoLSG name.
+func lsgID(district string, geoName string) string {
+	return strings.ToLower(districtCode(district)) + "-" + strings.Trim(reSlug.ReplaceAllString(strings.ToLower(geoName), "-"), "-")
+}
+
+// lsgFromGeoName derives a record from a GeoLSG name, reading the type from
+// the "(M)" and "(C)" suffixes.
+func lsgFromGeoName(district string, geoName string) LSG {
+	l := LSG{ID: lsgID(district, geoName), District: district, Name: geoName, GeoName: geoName, Type: LSG_GRAMA_PANCHAYAT}
+	if m := reTypeSuffix.FindStringSubmatch(geoName); m != nil {
+		l.Name = reTypeSuffix.ReplaceAllString(geoName, "")
+		l.Type = LSG_MUNICIPALITY
+		if m[1] == "C" {
+			l.Type = LSG_CORPORATION
+		}
+	}
+	return l
+}
+
+// NewGazetteer builds the gazetteer from GeoLSG, with the aliases of the
+// alias table as alternate names.
+func NewGazetteer() *Gazetteer {
+	g := &Gazetteer{byID: make(map[string]int), byName: make(map[string][]int)}
+	for _, d := range DistrictList {
+		for _, name := range GeoLSG[d] {
+			g.add(lsgFromGeoName(d, name))
+		}
+	}
+	for d, m := range LSGAliases {
+		for alias, name := range m {
+			for _, i := range g.byName[d+"/"+name] {
+				g.LSGs[i].AltNames = append(g.LSGs[i].AltNames, alias)
+			}
+		}
+	}
+	return g
+}
+
+// add inserts the record, merging it into the record with the same ID. The
+// ID defaults to the one derived from the GeoLSG name, so a record for a
+// listed LSG completes it and versions of an LSG need IDs of their own.
+// Empty fields of l don't overwrite known ones.
+func (g *Gazetteer) add(l LSG) {
+	if l.GeoName == "" {
+		l.GeoName = l.Name
+	}
+	if l.ID == "" {
+		l.ID = lsgID(l.District, l.GeoName)
+	}
+	i, ok := g.byID[l.ID]
+	if !ok {
+		derived := lsgFromGeoName(l.District, l.GeoName)
+		if l.Name == "" {
+			l.Name = derived.Name
+		}
+		if l.Type == "" {
+			l.Type = derived.Type
+		}
+		key := l.District + "/" + l.GeoName
+		g.byID[l.ID] = len(g.LSGs)
+		g.byName[key] = append(g.byName[key], len(g.LSGs))
+		g.LSGs = append(g.LSGs, l)
+		return
+	}
+	cur := &g.LSGs[i]
+	if l.Code != "" {
+		cur.Code = l.Code
+	}
+	if l.Name != "" {
+		cur.Name = l.Name
+	}
+	if l.Type != "" {
+		cur.Type = l.Type
+	}
+	if l.Block != "" {
+		cur.Block = l.Block
+	}
+	if l.Taluk != "" {
+		cur.Taluk = l.Taluk
+	}
+	if l.Wards != 0 {
+		cur.Wards = l.Wards
+	}
+	if l.Malayalam != "" {
+		cur.Malayalam = l.Malayalam
+	}
+	if l.From != "" {
+		cur.From = l.From
+	}
+	if l.To != "" {
+		cur.To = l.To
+	}
+	for _, a := range l.AltNames {
+		if !contains(cur.AltNames, a) {
+			cur.AltNames = append(cur.AltNames, a)
+		}
+	}
+	for _, id := range l.ReplacedBy {
+		if !contains(cur.ReplacedBy, id) {
+			cur.ReplacedBy = append(cur.ReplacedBy, id)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Load merges the records of a gazetteer file, if it exists, into g. Their
+// alternate names become aliases of the LSG.
+func (g *Gazetteer) Load(filename string) error {
+	s, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var lsgs []LSG
+	if err := json.Unmarshal(s, &lsgs); err != nil {
+		return err
+	}
+	for _, l := range lsgs {
+		if (l.From != "" && !validDate(l.From)) || (l.To != "" && !validDate(l.To)) {
+			return fmt.Errorf("invalid dates %q to %q for %v", l.From, l.To, l.GeoName)
+		}
+		g.add(l)
+		for _, a := range l.AltNames {
+			if _, ok := ResolveLSGAlias(l.District, a); !ok && a != l.GeoName {
+				AddLSGAlias(l.District, a, l.GeoName)
+			}
+		}
+	}
+	return nil
+}
+
+// ByID returns the LSG with the ID.
+func (g *Gazetteer) ByID(id string) (LSG, bool) {
+	i, ok := g.byID[id]
+	if !ok {
+		return LSG{}, false
+	}
+	return g.LSGs[i], true
+}
+
+func validDate(s string) bool {
+	_, err := ParseDate(s)
+	return err == nil
+}
+
+// ByCode returns the LSG with the LGD code.
+func (g *Gazetteer) ByCode(code string) (LSG, bool) {
+	for _, l := range g.LSGs {
+		if l.Code == code {
+			return l, true
+		}
+	}
+	return LSG{}, false
+}
+
+// Lookup returns the current LSG listed under the name in the district.
+func (g *Gazetteer) Lookup(district string, geoName string) (LSG, bool) {
+	return g.LookupAt(district, geoName, "")
+}
+
+// LookupAt returns the LSG listed under the name in the district that was
+// valid on the date, or any version of that name when none was.
+func (g *Gazetteer) LookupAt(district string, geoName string, date string) (LSG, bool) {
+	list := g.byName[district+"/"+geoName]
+	if len(list) == 0 {
+		return LSG{}, false
+	}
+	for _, i := range list {
+		if g.LSGs[i].ValidOn(date) {
+			return g.LSGs[i], true
+		}
+	}
+	return g.LSGs[list[0]], true
+}
+
+// Names returns the names of the LSGs of the district valid on the date,
+// the current ones for the empty date.
+func (g *Gazetteer) Names(district string, date string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, l := range g.LSGs {
+		if l.District == district && !seen[l.GeoName] && l.ValidOn(date) {
+			seen[l.GeoName] = true
+			names = append(names, l.GeoName)
+		}
+	}
+	return names
+}
+
+// matcher returns the matcher over the names, alternate names and
+// Malayalam names of every version of the district's LSGs.
+func (g *Gazetteer) matcher(district string) *Matcher {
+	g.matchersMu.Lock()
+	defer g.matchersMu.Unlock()
+	if g.matchers == nil {
+		g.matchers = make(map[string]*Matcher)
+	}
+	m, ok := g.matchers[district]
+	if !ok {
+		m = NewMatcher(nil)
+		for _, l := range g.LSGs {
+			if l.District != district {
+				continue
+			}
+			m.Add(l.GeoName, l.GeoName)
+			for _, a := range l.AltNames {
+				m.Add(a, l.GeoName)
+			}
+			if l.Malayalam != "" {
+				m.Add(l.Malayalam, l.GeoName)
+			}
+		}
+		g.matchers[district] = m
+	}
+	return m
+}
+
+// Match returns up to k LSGs of the district valid on the date that best
+// match the name, best first.
+func (g *Gazetteer) Match(district string, name string, date string, k int) []Match {
+	return g.matcher(district).Top(name, k, func(geoName string) bool {
+		l, ok := g.LookupAt(district, geoName, date)
+		return ok && l.ValidOn(date)
+	})
+}
+
+// LSGID returns the stable ID of the LSG listed under the name on the date,
+// the current one for the empty date. The ID doesn't change when the
+// gazetteer file adds an LGD code.
+func (g *Gazetteer) LSGID(district string, geoName string, date string) string {
+	l, ok := g.LookupAt(district, geoName, date)
+	if !ok {
+		return ""
+	}
+	return l.ID
+}
+
+// LSGGazetteer is the gazetteer used by the scrapers.
+var LSGGazetteer = NewGazetteer()
+
+// LoadGazetteer rebuilds LSGGazetteer from GeoLSG and the alias table, and
+// merges the gazetteer file into it. Load the LSG aliases first.
+func LoadGazetteer(filename string) error {
+	g := NewGazetteer()
+	if err := g.Load(filename); err != nil {
+		return err
+	}
+	LSGGazetteer = g
+	return nil
+}
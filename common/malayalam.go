@@ -0,0 +1,123 @@
+package common
+
+import (
+	"strings"
+	"unicode"
+)
+
+const virama = '്'
+
+// mlVowels are the independent vowels, mlSigns the vowel signs following a
+// consonant.
+var (
+	mlVowels = map[rune]string{
+		'അ': "a", 'ആ': "aa", 'ഇ': "i", 'ഈ': "ee", 'ഉ': "u", 'ഊ': "oo", 'ഋ': "ri",
+		'എ': "e", 'ഏ': "e", 'ഐ': "ai", 'ഒ': "o", 'ഓ': "o", 'ഔ': "au",
+	}
+	mlSigns = map[rune]string{
+		'ാ': "aa", 'ി': "i", 'ീ': "ee", 'ു': "u", 'ൂ': "oo", 'ൃ': "ri",
+		'െ': "e", 'േ': "e", 'ൈ': "ai", 'ൊ': "o", 'ോ': "o", 'ൌ': "au", 'ൗ': "au",
+	}
+	mlConsonants = map[rune]string{
+		'ക': "k", 'ഖ': "kh", 'ഗ': "g", 'ഘ': "gh", 'ങ': "ng",
+		'ച': "ch", 'ഛ': "chh", 'ജ': "j", 'ഝ': "jh", 'ഞ': "nj",
+		'ട': "d", 'ഠ': "th", 'ഡ': "d", 'ഢ': "dh", 'ണ': "n",
+		'ത': "th", 'ഥ': "th", 'ദ': "d", 'ധ': "dh", 'ന': "n",
+		'പ': "p", 'ഫ': "ph", 'ബ': "b", 'ഭ': "bh", 'മ': "m",
+		'യ': "y", 'ര': "r", 'ല': "l", 'വ': "v", 'ശ': "sh",
+		'ഷ': "sh", 'സ': "s", 'ഹ': "h", 'ള': "l", 'ഴ': "zh", 'റ': "r",
+	}
+	mlOthers = map[rune]string{
+		'ൺ': "n", 'ൻ': "n", 'ർ': "r", 'ൽ': "l", 'ൾ': "l", 'ൿ': "k",
+		'ം': "m", 'ഃ': "h",
+	}
+	// mlClusters are conjuncts not read letter by letter, with the way
+	// place names spell them in English.
+	mlClusters = strings.NewReplacer(
+		"ന്റ", "ന്ട", "റ്റ", "ട്ട", "ങ്ങ", "ങ", "ഞ്ച", "ന്ച",
+		"ശ്ശ", "സ്സ", "മ്പ", "മ്ബ", "ച്ച", "ച",
+	)
+	// mlKinds are the words saying what kind of LSG a name is, longest
+	// first, with the kind as returned by nameKind.
+	mlKinds = []struct{ word, kind string }{
+		{"മുനിസിപ്പൽ കോർപ്പറേഷൻ", "c"},
+		{"ഗ്രാമപഞ്ചായത്ത്", "p"},
+		{"ഗ്രാമ പഞ്ചായത്ത്", "p"},
+		{"മുനിസിപ്പാലിറ്റി", "m"},
+		{"കോർപ്പറേഷൻ", "c"},
+		{"കോർപറേഷൻ", "c"},
+		{"പഞ്ചായത്ത്", "p"},
+		{"നഗരസഭ", "m"},
+	}
+)
+
+// stripMalayalamKind removes the words saying what kind of LSG the name is
+// and returns the kind they said.
+func stripMalayalamKind(s string) (string, string) {
+	kind := ""
+	for _, k := range mlKinds {
+		if strings.Contains(s, k.word) {
+			s = strings.ReplaceAll(s, k.word, " ")
+			if kind == "" {
+				kind = k.kind
+			}
+		}
+	}
+	return s, kind
+}
+
+// HasMalayalam reports whether s contains Malayalam script.
+func HasMalayalam(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Malayalam, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transliterate writes the Malayalam script in s in Latin letters the way
+// place names are usually spelt in English, such as "Kozhikkod" for
+// "കോഴിക്കോട്". Other text is kept as is.
+func Transliterate(s string) string {
+	var b strings.Builder
+	runes := []rune(mlClusters.Replace(s))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if v, ok := mlVowels[r]; ok {
+			b.WriteString(v)
+			continue
+		}
+		if v, ok := mlOthers[r]; ok {
+			b.WriteString(v)
+			continue
+		}
+		c, ok := mlConsonants[r]
+		if !ok {
+			if r != virama && r != '‌' && r != '‍' {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		// ട doubled or after a nasal keeps its hard sound.
+		if r == 'ട' && i > 1 && runes[i-1] == virama && strings.ContainsRune("ടണന", runes[i-2]) {
+			c = "t"
+		} else if r == 'ട' && i+2 < len(runes) && runes[i+1] == virama && runes[i+2] == 'ട' {
+			c = "t"
+		}
+		b.WriteString(c)
+		if i+1 < len(runes) {
+			if v, ok := mlSigns[runes[i+1]]; ok {
+				b.WriteString(v)
+				i++
+				continue
+			}
+			if runes[i+1] == virama {
+				i++
+				continue
+			}
+		}
+		b.WriteString("a")
+	}
+	return b.String()
+}
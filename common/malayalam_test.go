@@ -0,0 +1,41 @@
+package common
+
+import "testing"
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"കോഴിക്കോട്", "kozhikkod"},
+		{"കൊല്ലം", "kollam"},
+		{"തിരുവനന്തപുരം", "thiruvananthapuram"},
+		{"ആലപ്പുഴ", "aalappuzha"},
+		{"പട്ടാമ്പി", "pattaambi"},
+		{"കണ്ടല", "kantala"},
+		{"മുന്നാർ", "munnaar"},
+		{"Ward 5", "Ward 5"},
+	}
+	for _, tt := range tests {
+		if got := Transliterate(tt.in); got != tt.want {
+			t.Errorf("Transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeMalayalam(t *testing.T) {
+	tests := []struct {
+		ml, en string
+	}{
+		{"കൊല്ലം", "Kollam"},
+		{"തിരുവനന്തപുരം", "Thiruvananthapuram"},
+		{"ആലപ്പുഴ", "Alappuzha"},
+		{"മുന്നാർ", "Munnar"},
+		{"ചവറ ഗ്രാമപഞ്ചായത്ത്", "Chavara"},
+	}
+	for _, tt := range tests {
+		if got, want := Normalize(tt.ml), Normalize(tt.en); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q as for %q", tt.ml, got, want, tt.en)
+		}
+	}
+}
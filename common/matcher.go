@@ -0,0 +1,252 @@
+package common
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Match is a candidate found by a Matcher. Match is the name matched and
+// Score how well it matched out of 100. Query and Key are the normalised
+// query and the normalised name it was compared with, Via the alternate
+// name matched when it isn't the name itself.
+type Match struct {
+	Match string
+	Score int
+	Query string
+	Key   string
+	Via   string
+}
+
+// Matcher finds the names closest to a query through an index of the
+// character trigrams of their normalised forms.
+type Matcher struct {
+	entries []matcherEntry
+	index   map[string][]int
+}
+
+type matcherEntry struct {
+	name, target, key, kind string
+	grams                   map[string]bool
+}
+
+var (
+	reKind = regexp.MustCompile(`(?i)\(\s*([mc])\s*\)|\b(municipality|corporation|grama\s+panchayat|panchayat|gp)\b`)
+	// reStopWords are words that only say what kind of place a name is.
+	reStopWords = regexp.MustCompile(`\b(municipality|municipal|corporation|grama|panchayath?|gp|lsgd?|district|dist)\b`)
+	reFinalY    = regexp.MustCompile(`y\b`)
+	// transliterations maps the spelling variants of Malayalam sounds in
+	// English to a single form.
+	transliterations = strings.NewReplacer("th", "t", "zh", "l", "oo", "u", "ee", "i", "ph", "f", "w", "v")
+)
+
+// nameKind returns "m" for municipalities, "c" for corporations, "p" for
+// panchayats and "" when the name doesn't say.
+func nameKind(s string) string {
+	if HasMalayalam(s) {
+		if _, kind := stripMalayalamKind(s); kind != "" {
+			return kind
+		}
+	}
+	m := reKind.FindStringSubmatch(s)
+	switch {
+	case m == nil:
+		return ""
+	case strings.EqualFold(m[1], "m") || strings.EqualFold(m[2], "municipality"):
+		return "m"
+	case strings.EqualFold(m[1], "c") || strings.EqualFold(m[2], "corporation"):
+		return "c"
+	}
+	return "p"
+}
+
+// Normalize reduces a place name to the form names are compared in: lower
+// case letters and digits only, without type suffixes and words like
+// "Panchayat", with transliteration variants folded, a final "y" read as
+// "i" and doubled letters collapsed. Malayalam script is transliterated
+// first.
+func Normalize(s string) string {
+	if HasMalayalam(s) {
+		s, _ = stripMalayalamKind(s)
+		s = Transliterate(s)
+	}
+	s = strings.ToLower(s)
+	s = reKind.ReplaceAllString(s, " ")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) {
+			return r
+		}
+		return ' '
+	}, s)
+	s = reStopWords.ReplaceAllString(s, " ")
+	s = transliterations.Replace(s)
+	s = reFinalY.ReplaceAllString(s, "i")
+	var b strings.Builder
+	var last rune
+	for _, r := range strings.Join(strings.Fields(s), " ") {
+		if r != last || r == ' ' {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+func trigrams(key string) map[string]bool {
+	r := []rune(" " + key + " ")
+	grams := make(map[string]bool)
+	for i := 0; i+3 <= len(r); i++ {
+		grams[string(r[i:i+3])] = true
+	}
+	return grams
+}
+
+// NewMatcher returns a matcher over the names.
+func NewMatcher(names []string) *Matcher {
+	m := &Matcher{index: make(map[string][]int)}
+	for _, n := range names {
+		m.Add(n, n)
+	}
+	return m
+}
+
+// Add indexes name as a way of writing target.
+func (m *Matcher) Add(name string, target string) {
+	e := matcherEntry{name: name, target: target, key: Normalize(name), kind: nameKind(name)}
+	if e.kind == "" {
+		e.kind = nameKind(target)
+	}
+	e.grams = trigrams(e.key)
+	for g := range e.grams {
+		m.index[g] = append(m.index[g], len(m.entries))
+	}
+	m.entries = append(m.entries, e)
+}
+
+// score rates an entry against the normalised query by the average of the
+// trigram overlap and the edit distance of the keys, lowered by 10 when
+// the query names a different kind of place.
+func score(e matcherEntry, key string, kind string, grams map[string]bool, shared int) int {
+	if e.key == key {
+		if kind != "" && e.kind != kind {
+			return 90
+		}
+		return 100
+	}
+	dice := 2 * float64(shared) / float64(len(grams)+len(e.grams))
+	a, b := []rune(key), []rune(e.key)
+	edit := 1 - float64(levenshtein(a, b))/math.Max(float64(len(a)), float64(len(b)))
+	s := int(math.Round(50*dice + 50*edit))
+	if kind != "" && e.kind != kind {
+		s -= 10
+	}
+	if s < 0 {
+		s = 0
+	}
+	return s
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Top returns up to k best matches for the query, best first, one per
+// target. Only names sharing a trigram with the query are considered, and
+// keep, when given, filters the targets.
+func (m *Matcher) Top(query string, k int, keep func(target string) bool) []Match {
+	key, kind := Normalize(query), nameKind(query)
+	grams := trigrams(key)
+	shared := make(map[int]int)
+	for g := range grams {
+		for _, i := range m.index[g] {
+			shared[i]++
+		}
+	}
+	best := make(map[string]Match)
+	for i, n := range shared {
+		e := m.entries[i]
+		if keep != nil && !keep(e.target) {
+			continue
+		}
+		s := score(e, key, kind, grams, n)
+		if cur, ok := best[e.target]; ok && cur.Score >= s {
+			continue
+		}
+		mt := Match{Match: e.target, Score: s, Query: key, Key: e.key}
+		if e.name != e.target {
+			mt.Via = e.name
+		}
+		best[e.target] = mt
+	}
+	matches := make([]Match, 0, len(best))
+	for _, v := range best {
+		matches = append(matches, v)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Match < matches[j].Match
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Best returns the best match for the query, or a match with an empty name
+// and a score of 0 when nothing is close.
+func (m *Matcher) Best(query string, keep func(target string) bool) *Match {
+	if top := m.Top(query, 1, keep); len(top) > 0 {
+		return &top[0]
+	}
+	return &Match{Query: Normalize(query)}
+}
+
+var (
+	districtMatcher     *Matcher
+	districtMatcherOnce sync.Once
+)
+
+// MatchDistrict resolves a district name, first through the district codes
+// and aliases and then by matching its English and Malayalam names.
+func MatchDistrict(name string) *Match {
+	if d, ok := ResolveDistrictAlias(name); ok {
+		return &Match{Match: d, Score: 100, Query: Normalize(name), Key: Normalize(d), Via: name}
+	}
+	districtMatcherOnce.Do(func() {
+		districtMatcher = NewMatcher(DistrictList)
+		for _, d := range DistrictList {
+			for _, ml := range DistrictMalayalam[d] {
+				districtMatcher.Add(ml, d)
+			}
+		}
+	})
+	return districtMatcher.Best(name, nil)
+}
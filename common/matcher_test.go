@@ -0,0 +1,26 @@
+package common
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Kollam Corporation", "kolam"},
+		{"Thiruvananthapuram (C)", "tiruvanantapuram"},
+		{"Alappuzha (M)", "alapula"},
+		{"Chavara Grama Panchayat", "chavara"},
+		{"Koothuparamba Municipality", "kutuparamba"},
+		{"Kozhikode", "kolikode"},
+		{"Kozhikkode", "kolikode"},
+		{"Cherppu", "cherpu"},
+		{"Aroor-Kuthiathode", "arur kutiatode"},
+		{"Ward 12", "vard 12"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
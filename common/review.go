@@ -0,0 +1,113 @@
+package common
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+)
+
+// REVIEW_THRESHOLD is the score below which an LSG match is queued for
+// review.
+const REVIEW_THRESHOLD = 60
+
+type Candidate struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+// ReviewItem is an LSG name no alias covers that fuzzy matching couldn't
+// resolve confidently.
+type ReviewItem struct {
+	ID         string      `json:"id"`
+	Raw        string      `json:"raw"`
+	District   string      `json:"district"`
+	Candidates []Candidate `json:"candidates"`
+	Source     string      `json:"source"`
+	// LastSeen is the date of the latest data the name appeared in, Count
+	// how many times it was queued.
+	LastSeen string `json:"last_seen"`
+	Count    int    `json:"count"`
+}
+
+type ReviewQueue struct {
+	mu    sync.Mutex
+	items []ReviewItem
+}
+
+// Reviews collects the low-confidence matches of a run until flushed.
+var Reviews = &ReviewQueue{}
+
+func reviewID(district string, raw string) string {
+	h := sha1.Sum([]byte(district + "/" + aliasKey(raw)))
+	return hex.EncodeToString(h[:4])
+}
+
+// Add queues the name with its best candidates in the district.
+func (q *ReviewQueue) Add(district string, raw string, source string, date string) {
+	item := ReviewItem{ID: reviewID(district, raw), Raw: raw, District: district, Source: source, LastSeen: date, Count: 1}
+	for _, m := range LSGGazetteer.Match(district, raw, date, 5) {
+		item.Candidates = append(item.Candidates, Candidate{Name: m.Match, Score: m.Score})
+	}
+	q.mu.Lock()
+	q.items = append(q.items, item)
+	q.mu.Unlock()
+}
+
+// ReadReviewQueue returns the items of the queue file, none when it doesn't
+// exist.
+func ReadReviewQueue(filename string) ([]ReviewItem, error) {
+	var items []ReviewItem
+	s, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return items, nil
+	}
+	if err != nil {
+		return items, err
+	}
+	err = json.Unmarshal(s, &items)
+	return items, err
+}
+
+func WriteReviewQueue(items []ReviewItem, filename string) error {
+	j, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, j, 0644)
+}
+
+// Flush merges the queued items into the queue file. Names already in the
+// file are counted again rather than duplicated.
+func (q *ReviewQueue) Flush(filename string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return nil
+	}
+	items, err := ReadReviewQueue(filename)
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int)
+	for i, v := range items {
+		index[v.ID] = i
+	}
+	for _, v := range q.items {
+		if i, ok := index[v.ID]; ok {
+			items[i].Count++
+			if DateBefore(items[i].LastSeen, v.LastSeen) {
+				items[i].LastSeen = v.LastSeen
+			}
+			continue
+		}
+		index[v.ID] = len(items)
+		items = append(items, v)
+	}
+	log.Printf("%v low-confidence matches queued for review\n", len(q.items))
+	q.items = nil
+	return WriteReviewQueue(items, filename)
+}
@@ -7,11 +7,13 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dlclark/regexp2"
-	fuzzy "github.com/paul-mannino/go-fuzzywuzzy"
 )
 
 func Atoi(s string) int {
@@ -26,12 +28,100 @@ func Itoa(i int64) string {
 	return strconv.FormatInt(i, 10)
 }
 
-func FuzzySearch(search string, targets []string) *fuzzy.MatchPair {
-	d, err := fuzzy.ExtractOne(search, targets)
+// DATE_LAYOUT is the dd-mm-yyyy layout used for every date in the datasets.
+const DATE_LAYOUT = "02-01-2006"
+
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse(DATE_LAYOUT, strings.TrimSpace(s))
+}
+
+func FormatDate(t time.Time) string {
+	return t.Format(DATE_LAYOUT)
+}
+
+// DateBefore reports whether the date a is earlier than b. Unparseable
+// dates sort first.
+func DateBefore(a, b string) bool {
+	ta, err := ParseDate(a)
 	if err != nil {
-		log.Panicln(err)
+		return true
+	}
+	tb, err := ParseDate(b)
+	if err != nil {
+		return false
+	}
+	return ta.Before(tb)
+}
+
+// DaysBetween returns the number of calendar days from a to b.
+func DaysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+var (
+	reAllWards = regexp.MustCompile(`(?i)\ball\b|\bentire\b|\bfull\b|\bwhole\b`)
+	reExcept   = regexp.MustCompile(`(?i)\bexcept(?:ing)?\b|\bexcluding\b|\bother\s+than\b|\bbut\s+not\b`)
+	reWardWord = regexp.MustCompile(`(?i)\bward(s)?\b|\bno\b\.?|\bnos\b\.?|\bnumbers?\b`)
+	reWardSep  = regexp.MustCompile(`(?i)\s*(?:,|&|;|/|\band\b)\s*`)
+	reWardSpan = regexp.MustCompile(`(?i)^(\d+)\s*(?:-|–|—|\bto\b)\s*(\d+)$`)
+	reWardNum  = regexp.MustCompile(`\d+`)
+)
+
+// wardNumbers returns the sorted ward numbers and ranges found in s.
+func wardNumbers(s string) []int {
+	seen := make(map[int]bool)
+	for _, part := range reWardSep.Split(reWardWord.ReplaceAllString(s, " "), -1) {
+		part = strings.Trim(strings.TrimSpace(part), ".:()")
+		if m := reWardSpan.FindStringSubmatch(part); m != nil {
+			from, _ := strconv.Atoi(m[1])
+			to, _ := strconv.Atoi(m[2])
+			// ranges are only trusted when short and ascending
+			if from <= to && to-from <= 100 {
+				for w := from; w <= to; w++ {
+					seen[w] = true
+				}
+				continue
+			}
+		}
+		for _, n := range reWardNum.FindAllString(part, -1) {
+			if w, err := strconv.Atoi(n); err == nil {
+				seen[w] = true
+			}
+		}
+	}
+	var wards []int
+	for w := range seen {
+		wards = append(wards, w)
+	}
+	sort.Ints(wards)
+	return wards
+}
+
+// ParseWards reads a ward list such as "Ward No. 3-7, 12 & 14" into sorted
+// ward numbers. Words such as "all" or "entire" only mean the whole LSG
+// when no ward numbers are listed, in which case all is true and except
+// holds the wards excluded by "except", as in "All wards except 3".
+func ParseWards(s string) (wards []int, all bool, except []int) {
+	listed := s
+	if loc := reExcept.FindStringIndex(s); loc != nil {
+		listed = s[:loc[0]]
+		except = wardNumbers(s[loc[1]:])
+	}
+	wards = wardNumbers(listed)
+	if len(wards) > 0 {
+		// "Wards 1-10 except 5"
+		var kept []int
+		for _, w := range wards {
+			if i := sort.SearchInts(except, w); i == len(except) || except[i] != w {
+				kept = append(kept, w)
+			}
+		}
+		return kept, false, nil
+	}
+	if len(except) > 0 || reAllWards.MatchString(listed) {
+		return nil, true, except
 	}
-	return d
+	return nil, false, nil
 }
 
 func ReadJSON(filename string, v interface{}) {
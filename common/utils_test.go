@@ -0,0 +1,42 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWards(t *testing.T) {
+	tests := []struct {
+		in     string
+		wards  []int
+		all    bool
+		except []int
+	}{
+		{"", nil, false, nil},
+		{"12", []int{12}, false, nil},
+		{"3, 7,12", []int{3, 7, 12}, false, nil},
+		{"3-7", []int{3, 4, 5, 6, 7}, false, nil},
+		{"3 to 5, 9", []int{3, 4, 5, 9}, false, nil},
+		{"4 & 9", []int{4, 9}, false, nil},
+		{"4, 6 and 9", []int{4, 6, 9}, false, nil},
+		{"Ward No. 3-7, 12 & 14", []int{3, 4, 5, 6, 7, 12, 14}, false, nil},
+		{"Ward Nos.2,11", []int{2, 11}, false, nil},
+		{"Wards 12, 3, 12", []int{3, 12}, false, nil},
+		{"All wards", nil, true, nil},
+		{"All Wards", nil, true, nil},
+		{"Entire Panchayat", nil, true, nil},
+		{"Ward 4 - full", []int{4}, false, nil},
+		{"Full ward 4", []int{4}, false, nil},
+		{"Entire panchayat except ward 3", nil, true, []int{3}},
+		{"All wards except 3, 5 & 8", nil, true, []int{3, 5, 8}},
+		{"Except ward 3", nil, true, []int{3}},
+		{"Wards 1-6 except 4", []int{1, 2, 3, 5, 6}, false, nil},
+		{"Ward 9-2", []int{2, 9}, false, nil},
+	}
+	for _, tt := range tests {
+		wards, all, except := ParseWards(tt.in)
+		if !reflect.DeepEqual(wards, tt.wards) || all != tt.all || !reflect.DeepEqual(except, tt.except) {
+			t.Errorf("ParseWards(%q) = %v, %v, %v, want %v, %v, %v", tt.in, wards, all, except, tt.wards, tt.all, tt.except)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package dhs
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/lu4p/cat"
+)
+
+const (
+	LANG_ENGLISH   = "en"
+	LANG_MALAYALAM = "ml"
+
+	KIND_BULLETIN = "bulletin"
+	KIND_ANNEXURE = "annexure"
+)
+
+type Attachment struct {
+	URL string `json:"url"`
+	// Name is the link text of the attachment in the post.
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	Kind     string `json:"kind"`
+}
+
+// Document is a downloaded attachment with its extracted text.
+type Document struct {
+	Attachment Attachment
+	PDF        []byte
+	Text       string
+}
+
+var (
+	reMalayalamName = regexp.MustCompile(`(?i)\bmal(ayalam)?\b|[_\-\s]mal[_\-\s.]|\bml\b`)
+	reEnglishName   = regexp.MustCompile(`(?i)\beng(lish)?\b|[_\-\s]eng[_\-\s.]|\ben\b`)
+	reAnnexureName  = regexp.MustCompile(`(?i)annex|appendix|list`)
+	reBulletinText  = regexp.MustCompile(`(?i)bulletin`)
+)
+
+// IsEnglishBulletin accepts the main bulletin in English.
+func IsEnglishBulletin(a Attachment) bool {
+	return a.Language != LANG_MALAYALAM && a.Kind == KIND_BULLETIN
+}
+
+// IsEnglish accepts the English bulletin and its annexures.
+func IsEnglish(a Attachment) bool {
+	return a.Language != LANG_MALAYALAM
+}
+
+// classifyName guesses the language and kind of an attachment from its file
+// name and link text. Unknown languages are left empty.
+func classifyName(a *Attachment) {
+	name := path.Base(a.URL) + " " + a.Name
+	switch {
+	case malayalamShare(a.Name) > 0.5 || reMalayalamName.MatchString(name):
+		a.Language = LANG_MALAYALAM
+	case reEnglishName.MatchString(name):
+		a.Language = LANG_ENGLISH
+	}
+	a.Kind = KIND_BULLETIN
+	if reAnnexureName.MatchString(name) {
+		a.Kind = KIND_ANNEXURE
+	}
+}
+
+// classifyContent settles the language and kind from the extracted text.
+// Text in Malayalam script is Malayalam, and a document that doesn't call
+// itself a bulletin near the top is an annexure.
+func classifyContent(a *Attachment, txt string) {
+	if malayalamShare(txt) > 0.3 {
+		a.Language = LANG_MALAYALAM
+	} else if a.Language == "" {
+		a.Language = LANG_ENGLISH
+	}
+	head := txt
+	if len(head) > 1000 {
+		head = head[:1000]
+	}
+	if a.Kind == KIND_BULLETIN && !reBulletinText.MatchString(head) {
+		a.Kind = KIND_ANNEXURE
+	}
+}
+
+// malayalamShare returns the fraction of letters in s that are in
+// Malayalam script.
+func malayalamShare(s string) float64 {
+	var letters, ml int
+	for _, r := range s {
+		if unicode.Is(unicode.Malayalam, r) {
+			ml++
+			letters++
+		} else if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters == 0 {
+		return 0
+	}
+	return float64(ml) / float64(letters)
+}
+
+// GetAttachments lists every pdf linked from the content of the post, in
+// the order they appear, classified by name.
+func GetAttachments(url string) ([]Attachment, error) {
+	body, code, err := request(url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if code != 200 {
+		return nil, errors.New("error retrieving bulletin post")
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, err
+	}
+	var attachments []Attachment
+	seen := make(map[string]bool)
+	doc.Find(".entry-content a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if !strings.HasSuffix(strings.ToLower(strings.Split(href, "?")[0]), ".pdf") {
+			return
+		}
+		if strings.HasPrefix(href, "/") {
+			href = DHS_URL + href
+		}
+		if seen[href] {
+			return
+		}
+		seen[href] = true
+		a := Attachment{URL: href, Name: strings.TrimSpace(s.Text())}
+		classifyName(&a)
+		attachments = append(attachments, a)
+	})
+	if len(attachments) == 0 {
+		return nil, errors.New("error finding the pdf in the bulletin post")
+	}
+	return attachments, nil
+}
+
+func downloadDocument(a Attachment) (Document, error) {
+	d := Document{Attachment: a}
+	body, code, err := request(a.URL)
+	if err != nil {
+		return d, err
+	}
+	defer body.Close()
+	if code != 200 {
+		return d, fmt.Errorf("error downloading %v: status %v", a.URL, code)
+	}
+	d.PDF, err = ioutil.ReadAll(body)
+	if err != nil {
+		return d, err
+	}
+	d.Text, err = cat.FromBytes(d.PDF)
+	if err != nil {
+		return d, err
+	}
+	classifyContent(&d.Attachment, d.Text)
+	return d, nil
+}
+
+// DownloadAttachments returns every attachment of the date's bulletin posts
+// accepted by want, main bulletins first. Bulletins are taken from the
+// default library, which downloads them on first use.
+func DownloadAttachments(date string, want func(Attachment) bool) ([]Document, error) {
+	return DefaultLibrary.Documents(date, want)
+}
@@ -0,0 +1,26 @@
+package dhs
+
+import "testing"
+
+func TestClassifyContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        Attachment
+		txt      string
+		language string
+		kind     string
+	}{
+		{"bulletin", Attachment{Kind: KIND_BULLETIN}, "Daily Bulletin 01-05-2020", LANG_ENGLISH, KIND_BULLETIN},
+		{"annexure", Attachment{Kind: KIND_BULLETIN}, "Annexure 1: List of Hotspots", LANG_ENGLISH, KIND_ANNEXURE},
+		{"untitled list", Attachment{Kind: KIND_BULLETIN}, "Sl. No  District  Local Body", LANG_ENGLISH, KIND_ANNEXURE},
+		{"named annexure", Attachment{Kind: KIND_ANNEXURE}, "Bulletin annexure", LANG_ENGLISH, KIND_ANNEXURE},
+		{"malayalam", Attachment{Language: LANG_ENGLISH, Kind: KIND_BULLETIN}, "ബുള്ളറ്റിൻ bulletin", LANG_MALAYALAM, KIND_BULLETIN},
+	}
+	for _, tt := range tests {
+		a := tt.a
+		classifyContent(&a, tt.txt)
+		if a.Language != tt.language || a.Kind != tt.kind {
+			t.Errorf("%v: got %v %v, want %v %v", tt.name, a.Language, a.Kind, tt.language, tt.kind)
+		}
+	}
+}
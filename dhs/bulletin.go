@@ -0,0 +1,142 @@
+package dhs
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	. "scrape/common"
+	"scrape/scraper"
+)
+
+// BULLETIN_SOURCE marks data taken from a DHS daily bulletin.
+const BULLETIN_SOURCE = "dhs-bulletin"
+
+type Bulletin struct {
+	Date       string
+	Hotspots   HotspotsHistory
+	TestReport *scraper.TestReport
+	// Summary holds the district-wise counts when the bulletin carries them.
+	Summary map[string]scraper.DistrictInfo
+}
+
+var (
+	reTestsTotal = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)total\s+(?:of\s+)?([\d,]+)\s+samples`),
+		regexp.MustCompile(`(?i)samples\s+(?:sent|tested)\s+so\s+far\s*:?\s*([\d,]+)`),
+	}
+	reTestsToday = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)last\s+24\s+hours,?\s+([\d,]+)\s+samples`),
+		regexp.MustCompile(`(?i)([\d,]+)\s+samples\s+(?:were|have\s+been)\s+tested\s+in\s+the\s+last\s+24\s+hours`),
+	}
+	rePositiveTotal = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)total\s+(?:number\s+of\s+)?(?:confirmed|positive)\s+cases\s*(?:so\s+far)?\s*:?\s*([\d,]+)`),
+	}
+	rePositiveToday = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)([\d,]+)\s+(?:new\s+)?(?:persons|people|cases)\s+(?:have\s+)?(?:been\s+)?(?:tested|confirmed)\s+(?:covid[-\s]?19\s+)?positive`),
+		regexp.MustCompile(`(?i)([\d,]+)\s+new\s+cases`),
+	}
+)
+
+// findCount returns the first number captured by any of the regexes.
+func findCount(txt string, res []*regexp.Regexp) (int, bool) {
+	for _, re := range res {
+		m := re.FindStringSubmatch(txt)
+		if m != nil {
+			return Atoi(strings.ReplaceAll(m[1], ",", "")), true
+		}
+	}
+	return 0, false
+}
+
+// parseTestReport reads the sample and positive counts from the bulletin
+// text. It returns nil, logging the first count it couldn't find, unless all
+// four are there.
+func parseTestReport(txt string, today string) *scraper.TestReport {
+	counts := []struct {
+		name string
+		res  []*regexp.Regexp
+		n    int
+	}{
+		{"total samples", reTestsTotal, 0},
+		{"samples today", reTestsToday, 0},
+		{"total positive", rePositiveTotal, 0},
+		{"positive today", rePositiveToday, 0},
+	}
+	for i := range counts {
+		n, ok := findCount(txt, counts[i].res)
+		if !ok {
+			log.Printf("no test report in bulletin (%v): %v not found\n", today, counts[i].name)
+			return nil
+		}
+		counts[i].n = n
+	}
+	total, tested, positive, todayPositive := counts[0].n, counts[1].n, counts[2].n, counts[3].n
+	return &scraper.TestReport{
+		Date:          today,
+		Total:         total,
+		Today:         tested,
+		Positive:      positive,
+		TodayPositive: todayPositive,
+		Source:        BULLETIN_SOURCE,
+	}
+}
+
+// ParseBulletin downloads the bulletin for the date and extracts every
+// dataset it can from it.
+func ParseBulletin(date string) (Bulletin, error) {
+	start := time.Now()
+	b := Bulletin{Date: date}
+	docs, err := DownloadAttachments(date, IsEnglish)
+	if err != nil {
+		return b, err
+	}
+	// the main bulletin comes first, annexures often carry the hotspot list
+	var tables []Table
+	for _, d := range docs {
+		t, err := ExtractTables(d.PDF)
+		if err != nil {
+			log.Printf("ERROR extracting tables from %v: %v\n", d.Attachment.URL, err)
+			continue
+		}
+		tables = append(tables, t...)
+	}
+	b.Hotspots = hotspotsFromTables(tables, date)
+	for _, d := range docs {
+		if len(b.Hotspots.Hotspots)+len(b.Hotspots.Added)+len(b.Hotspots.Removed) > 0 {
+			break
+		}
+		b.Hotspots = parseHotspots(d.Text, date)
+	}
+	b.TestReport = parseTestReport(docs[0].Text, date)
+	summary, err := summaryFromTables(tables)
+	for _, d := range docs {
+		if err == nil {
+			break
+		}
+		summary, err = parseDistrictTables(d.Text)
+	}
+	if err != nil {
+		log.Printf("no district counts in bulletin (%v): %v\n", date, err)
+	} else {
+		b.Summary = summary
+	}
+	log.Printf("parsed bulletin (%v) in %v\n", date, time.Now().Sub(start))
+	return b, nil
+}
+
+// History builds the day's history from the bulletin's district tables,
+// so the bulletin can stand in for the dashboard.
+func (b Bulletin) History(last scraper.History) (scraper.History, error) {
+	if b.Summary == nil {
+		return scraper.History{}, errors.New("no complete district tables in the bulletin")
+	}
+	return scraper.History{
+		Summary: b.Summary,
+		Delta:   scraper.ComputeDelta(b.Summary, last.Summary),
+		Date:    b.Date,
+		Source:  BULLETIN_SOURCE,
+	}, nil
+}
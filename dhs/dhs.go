@@ -1,30 +1,56 @@
 package dhs
 
 import (
-	"errors"
-	"io/ioutil"
 	"log"
-	"path"
 	"regexp"
 	"strings"
 	"time"
 
 	. "scrape/common"
-
-	"github.com/PuerkitoBio/goquery"
-	"github.com/lu4p/cat"
+	"scrape/scraper"
 )
 
 type Hotspots struct {
 	District string `json:"district"`
 	LSGD     string `json:"lsgd"`
+	Wards    string `json:"wards,omitempty"`
 }
 
+// HotspotsHistory is the hotspot list of a bulletin. Added and Removed are
+// the day's changes the bulletin lists separately from the full list.
 type HotspotsHistory struct {
 	Hotspots []Hotspots `json:"hotspots"`
+	Added    []Hotspots `json:"added,omitempty"`
+	Removed  []Hotspots `json:"removed,omitempty"`
 	Date     string     `json:"date"`
 }
 
+// ToHistory converts the full list and the day's changes to a hotspot
+// history marked as taken from the bulletin.
+func (history HotspotsHistory) ToHistory() scraper.HotspotsHistory {
+	return scraper.HotspotsHistory{
+		Hotspots: toHotspots(history.Hotspots, history.Date),
+		Added:    toHotspots(history.Added, history.Date),
+		Removed:  toHotspots(history.Removed, history.Date),
+		Date:     history.Date,
+		Source:   BULLETIN_SOURCE,
+	}
+}
+
+func toHotspots(list []Hotspots, date string) []scraper.Hotspots {
+	h := make([]scraper.Hotspots, 0)
+	for _, v := range list {
+		h = append(h, scraper.NewHotspots(v.District, v.LSGD, v.Wards, BULLETIN_SOURCE, date))
+	}
+	return h
+}
+
+const (
+	sectionList = iota
+	sectionAdded
+	sectionRemoved
+)
+
 var (
 	re1 = regexp.MustCompile(`Sl. (\012){0,1}(No.* ){0,1}District .*(\012No){0,1}`)
 	re2 = regexp.MustCompile(`\d{1,3}\s\s[a-zA-Z]{5,}\s\s[a-zA-Z]+.*\012`)
@@ -32,135 +58,161 @@ var (
 	re4 = regexp.MustCompile(`\s*\n`)
 	re5 = regexp.MustCompile(`\d{2}-\d{2}-\d{4}`)
 	re6 = regexp.MustCompile(`\s*(\(.\))\s*`)
+
+	reAddedTitle   = regexp.MustCompile(`(?i)new(ly)?\s+(added\s+)?hot\s*spots?|added|included`)
+	reRemovedTitle = regexp.MustCompile(`(?i)remov|exclu|delet|omitted|de-?notif`)
+	// titleChars is how much text before a table header is searched for
+	// its caption in the flattened text.
+	titleChars = 300
 )
 
+// GetBulletinPost returns the link of the first bulletin post for the date.
 func GetBulletinPost(date string) (string, error) {
-	url := "https://dhs.kerala.gov.in/category/daily-bulletin/"
-	var s []byte
-	var link string
-	i := 1
-	d := strings.Split(date, "-")
-	re := regexp.MustCompile(`/` + path.Join(d[2], d[1], d[0], date) + `(-2)*/`)
-	for {
-		body, code, err := MakeRequest(url)
-		if err != nil {
-			return "", err
-		}
-		if code == 404 {
-			return "", errors.New("error finding the bulletin post for the date")
-		}
-		defer body.Close()
-		s, err = ioutil.ReadAll(body)
-		if err != nil {
-			return "", err
-		}
-		link = re.FindString(string(s))
-		if link != "" {
-			break
-		}
-		i++
-		url = "https://dhs.kerala.gov.in/category/daily-bulletin/" + "page/" + Itoa(int64(i)) + "/"
+	posts, err := FindBulletinPosts(date)
+	if err != nil {
+		return "", err
 	}
-	return "https://dhs.kerala.gov.in" + link, nil
+	return posts[0].URL, nil
 }
 
+// GetPDFURL returns the English bulletin attached to the post, falling
+// back to the first attachment when none is labelled.
 func GetPDFURL(url string) (string, error) {
-	body, code, err := MakeRequest(url)
-	defer body.Close()
+	attachments, err := GetAttachments(url)
 	if err != nil {
 		return "", err
 	}
-	if code != 200 {
-		return "", errors.New("error retrieving bulletin post")
-	}
-	doc, err := goquery.NewDocumentFromReader(body)
-	if err != nil {
-		return "", err
-	}
-	s, exists := doc.Find(".entry-content > p:nth-child(1) > a:nth-child(1)").Attr("href")
-	if !exists {
-		s, exists = doc.Find(".entry-content > ul:nth-child(1) > li:nth-child(1) > a:nth-child(1)").Attr("href")
-		if !exists {
-			s, exists = doc.Find(".entry-content > ul:nth-child(1) > li:nth-child(1) > strong:nth-child(1) > a:nth-child(1)").Attr("href")
-			if !exists {
-				return "", errors.New("error finding the pdf in the bulletin post")
-			}
+	for _, a := range attachments {
+		if a.Language == LANG_ENGLISH && a.Kind == KIND_BULLETIN {
+			return a.URL, nil
 		}
 	}
-	return "https://dhs.kerala.gov.in" + s, nil
+	return attachments[0].URL, nil
 }
 
+// DownloadPDF downloads the main English bulletin for the date.
 func DownloadPDF(date string) ([]byte, error) {
-	url, err := GetBulletinPost(date)
+	docs, err := DownloadAttachments(date, IsEnglishBulletin)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("retrieving pdf from url: %v", url)
-	pdfurl, err := GetPDFURL(url)
+	return docs[0].PDF, nil
+}
+
+// ParseHotspotHistory parses the hotspot lists of the date's bulletin. The
+// main run takes them from its single ParseBulletin of the day instead, so
+// the bulletin is read once for every dataset.
+func ParseHotspotHistory(today string) (HotspotsHistory, error) {
+	start := time.Now()
+	b, err := ParseBulletin(today)
 	if err != nil {
-		return nil, err
+		return HotspotsHistory{Hotspots: make([]Hotspots, 0), Date: today}, err
 	}
-	body, code, err := MakeRequest(pdfurl)
-	if err != nil {
-		return nil, err
+	log.Printf("parsed latest hotspot history (%v) in %v with %v entries, %v added and %v removed\n", today, time.Now().Sub(start), len(b.Hotspots.Hotspots), len(b.Hotspots.Added), len(b.Hotspots.Removed))
+	return b.Hotspots, nil
+}
+
+// hotspotSection tells from the caption of a table whether it lists new
+// hotspots, removed hotspots or the full list.
+func hotspotSection(title string) int {
+	switch {
+	case reRemovedTitle.MatchString(title):
+		return sectionRemoved
+	case reAddedTitle.MatchString(title):
+		return sectionAdded
 	}
-	defer body.Close()
-	if code != 200 {
-		return nil, errors.New("error downloading the pdf")
+	return sectionList
+}
+
+// addHotspot resolves the district and LSG names and appends the hotspot to
+// the section unless it is already listed there. Names that can't be matched
+// confidently are queued for review instead.
+func (history *HotspotsHistory) addHotspot(section int, district string, lsg string, wards string) {
+	d := MatchDistrict(district)
+	s := MatchLSG(d.Match, lsg, history.Date)
+	if s.Score < REVIEW_THRESHOLD || d.Score < REVIEW_THRESHOLD {
+		Reviews.Add(d.Match, lsg, BULLETIN_SOURCE, history.Date)
+		return
 	}
-	s, err := ioutil.ReadAll(body)
-	if err != nil {
-		return nil, err
+	list := &history.Hotspots
+	switch section {
+	case sectionAdded:
+		list = &history.Added
+	case sectionRemoved:
+		list = &history.Removed
+	}
+	for _, a := range *list {
+		if a.District == d.Match && a.LSGD == s.Match {
+			return
+		}
 	}
-	return s, nil
+	*list = append(*list, Hotspots{District: d.Match, LSGD: s.Match, Wards: strings.TrimSpace(wards)})
 }
 
-func ParseHotspotHistory(today string) (HotspotsHistory, error) {
-	start := time.Now()
+// parseHotspots reads the hotspot tables from the flattened bulletin text.
+// Each table is put in a section by the text just before its header.
+func parseHotspots(txt string, today string) HotspotsHistory {
 	history := HotspotsHistory{Hotspots: make([]Hotspots, 0), Date: today}
-	pdf, err := DownloadPDF(today)
-	txt, err := cat.FromBytes(pdf)
-	if err != nil {
-		return history, err
-	}
-	data := re2.FindAllString(re1.Split(txt, 2)[1], -1)
-	for _, l := range data {
-		place := strings.Split(re4.ReplaceAllString(re3.ReplaceAllString(l, ""), ""), "  ")
-		if len(place) < 3 {
-			continue
-		}
-		if place[2] == "Koothuparamba (M)" {
-			place[2] = "Kuthuparambu (M)"
+	parts := re1.Split(txt, -1)
+	for i := 1; i < len(parts); i++ {
+		title := parts[i-1]
+		if len(title) > titleChars {
+			title = title[len(title)-titleChars:]
 		}
-		if place[2] == "Mattanur (M)" {
-			place[2] = "Mattannoor (M)"
-		}
-		if place[2] == "Maloor" {
-			place[2] = "Malur"
-		}
-		if place[2] == "Changanacherry (M)" {
-			place[2] = "Changanassery (M)"
+		section := hotspotSection(title)
+		data := re2.FindAllString(parts[i], -1)
+		for _, l := range data {
+			place := strings.Split(re4.ReplaceAllString(re3.ReplaceAllString(l, ""), ""), "  ")
+			if len(place) < 3 {
+				continue
+			}
+			wards := ""
+			if len(place) > 3 {
+				wards = strings.Join(place[3:], " ")
+			}
+			history.addHotspot(section, place[1], place[2], wards)
 		}
-		if place[2] == "District Hospital" {
-			place[2] = "Marutharoad"
+	}
+	return history
+}
+
+var (
+	reDistrictHeader = regexp.MustCompile(`(?i)district`)
+	reLSGHeader      = regexp.MustCompile(`(?i)local\s*body|lsg|panchayat|municipality|corporation|name`)
+	reWardHeader     = regexp.MustCompile(`(?i)ward`)
+)
+
+// hotspotsFromTables reads the hotspot tables from the tables reconstructed
+// from the bulletin layout, putting each in a section by its caption.
+// Wrapped LSG names and ward lists have already been joined.
+func hotspotsFromTables(tables []Table, today string) HotspotsHistory {
+	history := HotspotsHistory{Hotspots: make([]Hotspots, 0), Date: today}
+	for _, t := range tables {
+		dc, lc, wc := -1, -1, -1
+		for i, h := range t.Header {
+			if dc < 0 && reDistrictHeader.MatchString(h) {
+				dc = i
+			} else if wc < 0 && reWardHeader.MatchString(h) {
+				wc = i
+			} else if lc < 0 && reLSGHeader.MatchString(h) {
+				lc = i
+			}
 		}
-		d := FuzzySearch(place[1], DistrictList)
-		s := FuzzySearch(place[2], GeoLSG[d.Match])
-		if s.Score < 60 || d.Score < 60 {
+		if dc < 0 || lc < 0 {
 			continue
-			// return history, errors.New(place[2] + s.Match)
 		}
-		fl := true
-		for _, a := range history.Hotspots {
-			if a.District == d.Match && a.LSGD == s.Match {
-				fl = false
-				break
+		section := hotspotSection(t.Title)
+		for _, r := range t.Rows {
+			lsg := strings.TrimSpace(re3.ReplaceAllString(r[lc], ""))
+			if r[dc] == "" || lsg == "" {
+				continue
 			}
-		}
-		if fl {
-			history.Hotspots = append(history.Hotspots, Hotspots{District: d.Match, LSGD: s.Match})
+			wards := ""
+			if wc >= 0 {
+				wards = r[wc]
+			}
+			history.addHotspot(section, r[dc], lsg, wards)
 		}
 	}
-	log.Printf("parsed latest hotspot history (%v) in %v with %v entries\n", today, time.Now().Sub(start), len(history.Hotspots))
-	return history, nil
+	return history
 }
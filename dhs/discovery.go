@@ -0,0 +1,263 @@
+package dhs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	. "scrape/common"
+)
+
+const (
+	DHS_URL       = "https://dhs.kerala.gov.in"
+	BULLETIN_SLUG = "daily-bulletin"
+	// MAX_PAGES bounds how many listing pages are walked per source.
+	MAX_PAGES = 10
+	// SEARCH_WINDOW is how many days around the bulletin date posts are
+	// looked for, as bulletins are sometimes posted the next day.
+	SEARCH_WINDOW = 2
+	// REQUEST_TIMEOUT bounds every request to the DHS site, including
+	// reading the body.
+	REQUEST_TIMEOUT = time.Minute
+)
+
+var client = &http.Client{Timeout: REQUEST_TIMEOUT}
+
+// request gets the url with the timeout of the DHS client.
+func request(url string) (io.ReadCloser, int, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Body, res.StatusCode, nil
+}
+
+type BulletinPost struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Date is the bulletin date the post is for.
+	Date string `json:"date"`
+	// Suffix is the number WordPress appends to the slug of a second post
+	// on the same date, 1 for the first post.
+	Suffix    int       `json:"suffix"`
+	Published time.Time `json:"published"`
+}
+
+var reTags = regexp.MustCompile(`<[^>]*>`)
+
+// postPattern matches the link of any post for the date, whatever day it
+// was published on.
+func postPattern(date string) *regexp.Regexp {
+	return regexp.MustCompile(`/\d{4}/\d{2}/\d{2}/` + regexp.QuoteMeta(date) + `(?:-(\d+))?/?$`)
+}
+
+// candidate returns the post if its link is for the date.
+func candidate(re *regexp.Regexp, date string, link string, title string, published time.Time) (BulletinPost, bool) {
+	m := re.FindStringSubmatch(strings.TrimSpace(link))
+	if m == nil {
+		return BulletinPost{}, false
+	}
+	suffix := 1
+	if m[1] != "" {
+		suffix = Atoi(m[1])
+	}
+	title = strings.TrimSpace(reTags.ReplaceAllString(title, ""))
+	return BulletinPost{URL: strings.TrimSpace(link), Title: title, Date: date, Suffix: suffix, Published: published}, true
+}
+
+func readAll(url string) ([]byte, error) {
+	body, code, err := request(url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if code != 200 {
+		return nil, fmt.Errorf("error requesting %v: status %v", url, code)
+	}
+	return ioutil.ReadAll(body)
+}
+
+// restPosts searches the WordPress REST API for posts of the bulletin
+// category published within the window around the date.
+func restPosts(date string, from time.Time, to time.Time) ([]BulletinPost, error) {
+	var posts []BulletinPost
+	s, err := readAll(DHS_URL + "/wp-json/wp/v2/categories?slug=" + BULLETIN_SLUG)
+	if err != nil {
+		return posts, err
+	}
+	var categories []struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(s, &categories); err != nil {
+		return posts, err
+	}
+	if len(categories) == 0 {
+		return posts, errors.New("error finding the bulletin category")
+	}
+	re := postPattern(date)
+	for page := 1; page <= MAX_PAGES; page++ {
+		url := fmt.Sprintf("%v/wp-json/wp/v2/posts?categories=%v&after=%v&before=%v&per_page=100&page=%v",
+			DHS_URL, categories[0].ID, from.Format("2006-01-02T15:04:05"), to.Format("2006-01-02T15:04:05"), page)
+		body, code, err := request(url)
+		if err != nil {
+			return posts, err
+		}
+		s, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return posts, err
+		}
+		// WordPress answers 400 once the page is past the last one
+		if code == 400 {
+			break
+		}
+		if code != 200 {
+			return posts, fmt.Errorf("error searching bulletin posts: status %v", code)
+		}
+		var res []struct {
+			Link  string `json:"link"`
+			Date  string `json:"date"`
+			Title struct {
+				Rendered string `json:"rendered"`
+			} `json:"title"`
+		}
+		if err := json.Unmarshal(s, &res); err != nil {
+			return posts, err
+		}
+		for _, r := range res {
+			published, _ := time.Parse("2006-01-02T15:04:05", r.Date)
+			if p, ok := candidate(re, date, r.Link, r.Title.Rendered, published); ok {
+				posts = append(posts, p)
+			}
+		}
+		if len(res) < 100 {
+			break
+		}
+	}
+	return posts, nil
+}
+
+// feedPosts walks the RSS feed of the bulletin category until it passes the
+// start of the window.
+func feedPosts(date string, from time.Time, to time.Time) ([]BulletinPost, error) {
+	var posts []BulletinPost
+	re := postPattern(date)
+	for page := 1; page <= MAX_PAGES; page++ {
+		s, err := readAll(fmt.Sprintf("%v/category/%v/feed/?paged=%v", DHS_URL, BULLETIN_SLUG, page))
+		if err != nil {
+			if page > 1 {
+				break
+			}
+			return posts, err
+		}
+		var feed struct {
+			Items []struct {
+				Title   string `xml:"title"`
+				Link    string `xml:"link"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"channel>item"`
+		}
+		if err := xml.Unmarshal(s, &feed); err != nil {
+			return posts, err
+		}
+		if len(feed.Items) == 0 {
+			break
+		}
+		older := false
+		for _, it := range feed.Items {
+			published, err := time.Parse(time.RFC1123Z, strings.TrimSpace(it.PubDate))
+			if err == nil && published.Before(from) {
+				older = true
+			}
+			if err == nil && published.After(to) {
+				continue
+			}
+			if p, ok := candidate(re, date, it.Link, it.Title, published); ok {
+				posts = append(posts, p)
+			}
+		}
+		if older {
+			break
+		}
+	}
+	return posts, nil
+}
+
+// listingPosts scans the HTML listing of the bulletin category, which is all
+// that is left when neither the API nor the feed respond.
+func listingPosts(date string) ([]BulletinPost, error) {
+	var posts []BulletinPost
+	re := regexp.MustCompile(`/\d{4}/\d{2}/\d{2}/` + regexp.QuoteMeta(date) + `(?:-\d+)?/`)
+	url := DHS_URL + "/category/" + BULLETIN_SLUG + "/"
+	for page := 1; page <= MAX_PAGES; page++ {
+		if page > 1 {
+			url = DHS_URL + "/category/" + BULLETIN_SLUG + "/page/" + Itoa(int64(page)) + "/"
+		}
+		s, err := readAll(url)
+		if err != nil {
+			if page > 1 {
+				break
+			}
+			return posts, err
+		}
+		for _, link := range re.FindAllString(string(s), -1) {
+			if p, ok := candidate(postPattern(date), date, DHS_URL+link, "", time.Time{}); ok {
+				posts = append(posts, p)
+			}
+		}
+		if len(posts) > 0 {
+			break
+		}
+	}
+	return posts, nil
+}
+
+// FindBulletinPosts returns the bulletin posts for the date, the first post
+// of the day first. It searches the REST API, then the RSS feed, then the
+// category listing, each bounded to MAX_PAGES pages.
+func FindBulletinPosts(date string) ([]BulletinPost, error) {
+	t, err := ParseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	from := t.AddDate(0, 0, -SEARCH_WINDOW)
+	to := t.AddDate(0, 0, SEARCH_WINDOW+1)
+	sources := []func() ([]BulletinPost, error){
+		func() ([]BulletinPost, error) { return restPosts(date, from, to) },
+		func() ([]BulletinPost, error) { return feedPosts(date, from, to) },
+		func() ([]BulletinPost, error) { return listingPosts(date) },
+	}
+	var posts []BulletinPost
+	for _, source := range sources {
+		posts, err = source()
+		if err == nil && len(posts) > 0 {
+			break
+		}
+	}
+	if len(posts) == 0 {
+		if err == nil {
+			err = errors.New("error finding the bulletin post for the date")
+		}
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	unique := posts[:0]
+	for _, p := range posts {
+		if !seen[p.URL] {
+			seen[p.URL] = true
+			unique = append(unique, p)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		return unique[i].Suffix < unique[j].Suffix
+	})
+	return unique, nil
+}
@@ -0,0 +1,247 @@
+package dhs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	. "scrape/common"
+	"scrape/scraper"
+)
+
+type column struct {
+	name string
+	re   *regexp.Regexp
+	set  func(*scraper.DistrictInfo, int)
+}
+
+var (
+	caseColumns = []column{
+		{"confirmed", regexp.MustCompile(`(?i)confirmed|positive|total\s+cases`), func(d *scraper.DistrictInfo, v int) { d.Confirmed = v }},
+		{"recovered", regexp.MustCompile(`(?i)recovered|cured|discharged`), func(d *scraper.DistrictInfo, v int) { d.Recovered = v }},
+		{"active", regexp.MustCompile(`(?i)active|under\s+treatment`), func(d *scraper.DistrictInfo, v int) { d.Active = v }},
+		{"deceased", regexp.MustCompile(`(?i)death|deceased|died`), func(d *scraper.DistrictInfo, v int) { d.Deceased = v }},
+	}
+	observationColumns = []column{
+		{"total observation", regexp.MustCompile(`(?i)\btotal\b`), func(d *scraper.DistrictInfo, v int) { d.TotalObservation = v }},
+		{"home observation", regexp.MustCompile(`(?i)home`), func(d *scraper.DistrictInfo, v int) { d.HomeObservation = v }},
+		{"hospital observation", regexp.MustCompile(`(?i)isolation`), func(d *scraper.DistrictInfo, v int) { d.HospitalObservation = v }},
+		{"hospitalized today", regexp.MustCompile(`(?i)(hospitali[sz]ed|admitted)\s+today`), func(d *scraper.DistrictInfo, v int) { d.HospitalizedToday = v }},
+	}
+	reObservation = regexp.MustCompile(`(?i)observation|quarantine`)
+	reDaily       = regexp.MustCompile(`(?i)today|new|24\s*h`)
+	reTableRow    = regexp.MustCompile(`^\s*(?:\d{1,2}\.?\s+)?([A-Za-z][A-Za-z ]+?)\s{2,}((?:[\d,]+\s*)+)$`)
+	reNumber      = regexp.MustCompile(`\d[\d,]*`)
+	// headerLines is how many lines above a table are searched for its header.
+	headerLines = 8
+)
+
+// tableValues records the columns read for each district.
+type tableValues map[string]map[string]bool
+
+// set stores the value of the column and records it as read.
+func (tv tableValues) set(district string, c column, info *scraper.DistrictInfo, v int) {
+	c.set(info, v)
+	if tv[district] == nil {
+		tv[district] = make(map[string]bool)
+	}
+	tv[district][c.name] = true
+}
+
+// complete returns an error naming the districts some case or observation
+// column wasn't read for, so a bulletin whose tables cover only part of the
+// state isn't taken for the day's counts.
+func (tv tableValues) complete() error {
+	var missing []string
+	for _, d := range DistrictList {
+		var cols []string
+		for _, columns := range [][]column{caseColumns, observationColumns} {
+			for _, c := range columns {
+				if !tv[d][c.name] {
+					cols = append(cols, c.name)
+				}
+			}
+		}
+		if len(cols) > 0 {
+			missing = append(missing, fmt.Sprintf("%v (%v)", d, strings.Join(cols, ", ")))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("district tables missing %v", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+type districtRow struct {
+	district string
+	values   []int
+}
+
+// headerColumns returns the columns named in the header text, in the order
+// they appear.
+func headerColumns(header string, columns []column) []column {
+	type found struct {
+		at  int
+		col column
+	}
+	var fs []found
+	for _, c := range columns {
+		if loc := c.re.FindStringIndex(header); loc != nil {
+			fs = append(fs, found{loc[0], c})
+		}
+	}
+	sort.Slice(fs, func(i, j int) bool { return fs[i].at < fs[j].at })
+	out := make([]column, len(fs))
+	for i, f := range fs {
+		out[i] = f.col
+	}
+	return out
+}
+
+// parseDistrictRow matches lines that start with a district name followed
+// only by numbers.
+func parseDistrictRow(line string) (districtRow, bool) {
+	m := reTableRow.FindStringSubmatch(line)
+	if m == nil {
+		return districtRow{}, false
+	}
+	d := MatchDistrict(strings.TrimSpace(m[1]))
+	if d == nil || d.Score < 80 {
+		return districtRow{}, false
+	}
+	row := districtRow{district: d.Match}
+	for _, n := range reNumber.FindAllString(m[2], -1) {
+		row.values = append(row.values, Atoi(strings.ReplaceAll(n, ",", "")))
+	}
+	return row, true
+}
+
+// applyTable stores a block of district rows into summary using the columns
+// named in its header. Blocks whose width doesn't match the header or that
+// don't cover every district are ignored.
+func applyTable(summary map[string]scraper.DistrictInfo, read tableValues, header string, rows []districtRow) {
+	if len(rows) < len(DistrictList) {
+		return
+	}
+	columns := caseColumns
+	if reObservation.MatchString(header) {
+		columns = observationColumns
+	}
+	cols := headerColumns(header, columns)
+	if len(cols) == 0 {
+		return
+	}
+	for _, r := range rows {
+		if len(r.values) != len(cols) {
+			return
+		}
+	}
+	for _, r := range rows {
+		info := summary[r.district]
+		for i, c := range cols {
+			read.set(r.district, c, &info, r.values[i])
+		}
+		summary[r.district] = info
+	}
+}
+
+// parseDistrictTables extracts the district-wise case and observation
+// tables from the bulletin text. It fails unless every column was read for
+// every district.
+func parseDistrictTables(txt string) (map[string]scraper.DistrictInfo, error) {
+	summary := make(map[string]scraper.DistrictInfo)
+	read := make(tableValues)
+	lines := strings.Split(txt, "\n")
+	var rows []districtRow
+	start := 0
+	flush := func() {
+		if len(rows) > 0 {
+			from := start - headerLines
+			if from < 0 {
+				from = 0
+			}
+			applyTable(summary, read, strings.Join(lines[from:start], " "), rows)
+		}
+		rows = nil
+	}
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		r, ok := parseDistrictRow(l)
+		if !ok {
+			flush()
+			continue
+		}
+		if len(rows) == 0 {
+			start = i
+		}
+		rows = append(rows, r)
+	}
+	flush()
+	if err := read.complete(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// summaryFromTables reads the district-wise case and observation counts from
+// the tables reconstructed from the bulletin layout, mapping every column by
+// its own header. It fails unless every column was read for every district.
+func summaryFromTables(tables []Table) (map[string]scraper.DistrictInfo, error) {
+	summary := make(map[string]scraper.DistrictInfo)
+	read := make(tableValues)
+	for _, t := range tables {
+		dc := -1
+		for i, h := range t.Header {
+			if reDistrictHeader.MatchString(h) {
+				dc = i
+				break
+			}
+		}
+		if dc < 0 {
+			continue
+		}
+		columns := caseColumns
+		observation := reObservation.MatchString(strings.Join(t.Header, " "))
+		if observation {
+			columns = observationColumns
+		}
+		setters := make(map[int]column)
+		for i, h := range t.Header {
+			// the case tables may also carry the day's new counts
+			if i == dc || (!observation && reDaily.MatchString(h)) {
+				continue
+			}
+			for _, c := range columns {
+				if c.re.MatchString(h) {
+					setters[i] = c
+					break
+				}
+			}
+		}
+		if len(setters) == 0 {
+			continue
+		}
+		for _, r := range t.Rows {
+			d := MatchDistrict(r[dc])
+			if d == nil || d.Score < 80 {
+				continue
+			}
+			info := summary[d.Match]
+			for i, c := range setters {
+				n := reNumber.FindString(r[i])
+				if n == "" {
+					continue
+				}
+				read.set(d.Match, c, &info, Atoi(strings.ReplaceAll(n, ",", "")))
+			}
+			summary[d.Match] = info
+		}
+	}
+	if err := read.complete(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
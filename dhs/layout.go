@@ -0,0 +1,351 @@
+package dhs
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lu4p/unipdf/v3/extractor"
+	pdfmodel "github.com/lu4p/unipdf/v3/model"
+)
+
+// box is a run of text on a page with its bounding box in PDF coordinates,
+// where y grows upwards.
+type box struct {
+	text           string
+	x0, x1, y0, y1 float64
+}
+
+func (b box) height() float64 {
+	return b.y1 - b.y0
+}
+
+// line is a set of boxes sharing a baseline, ordered left to right.
+type line struct {
+	boxes []box
+	y     float64
+	h     float64
+}
+
+func (l line) text() string {
+	var s []string
+	for _, b := range l.boxes {
+		s = append(s, b.text)
+	}
+	return strings.Join(s, "  ")
+}
+
+// Table is a table reconstructed from text positions. Cells that wrap over
+// several lines in the PDF are joined with a space.
+type Table struct {
+	// Title is the text just above the header, usually the caption.
+	Title  string
+	Header []string
+	Rows   [][]string
+}
+
+// tableColumn is the horizontal span of a table column and its header text.
+type tableColumn struct {
+	x0, x1 float64
+	header []string
+}
+
+var (
+	reSerial = regexp.MustCompile(`^\d{1,4}\.?$`)
+	reHeader = regexp.MustCompile(`(?i)district|sl\.?\s*no`)
+	reTotal  = regexp.MustCompile(`(?i)^total`)
+)
+
+// extractLines returns the text lines of every page of the pdf, rebuilt from
+// the positions of the individual text marks.
+func extractLines(pdf []byte) ([][]line, error) {
+	reader, err := pdfmodel.NewPdfReader(bytes.NewReader(pdf))
+	if err != nil {
+		return nil, err
+	}
+	n, err := reader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	pages := make([][]line, 0, n)
+	for i := 1; i <= n; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return nil, err
+		}
+		ex, err := extractor.New(page)
+		if err != nil {
+			return nil, err
+		}
+		pt, _, _, err := ex.ExtractPageText()
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, groupLines(pt.Marks().Elements()))
+	}
+	return pages, nil
+}
+
+// groupLines clusters marks into lines by their vertical position and joins
+// neighbouring marks into boxes. A horizontal gap wider than a space splits
+// a line into separate boxes.
+func groupLines(marks []extractor.TextMark) []line {
+	var glyphs []box
+	for _, m := range marks {
+		if m.Meta || strings.TrimSpace(m.Text) == "" {
+			continue
+		}
+		r := m.BBox
+		glyphs = append(glyphs, box{
+			text: m.Text,
+			x0:   math.Min(r.Llx, r.Urx), x1: math.Max(r.Llx, r.Urx),
+			y0: math.Min(r.Lly, r.Ury), y1: math.Max(r.Lly, r.Ury),
+		})
+	}
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		return glyphs[i].y0 > glyphs[j].y0
+	})
+	var lines []line
+	for _, g := range glyphs {
+		n := len(lines)
+		tol := math.Max(g.height()*0.4, 1)
+		if n > 0 && math.Abs(lines[n-1].y-g.y0) <= tol {
+			lines[n-1].boxes = append(lines[n-1].boxes, g)
+			lines[n-1].h = math.Max(lines[n-1].h, g.height())
+			continue
+		}
+		lines = append(lines, line{boxes: []box{g}, y: g.y0, h: g.height()})
+	}
+	for i := range lines {
+		lines[i].boxes = joinBoxes(lines[i].boxes, lines[i].h)
+	}
+	return lines
+}
+
+// joinBoxes merges glyphs of one line into words and words into cells.
+// Glyphs closer than a quarter of the line height belong to one word, words
+// closer than the line height belong to one cell.
+func joinBoxes(glyphs []box, h float64) []box {
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		return glyphs[i].x0 < glyphs[j].x0
+	})
+	var out []box
+	for _, g := range glyphs {
+		n := len(out)
+		if n > 0 {
+			gap := g.x0 - out[n-1].x1
+			if gap < h*0.25 {
+				out[n-1].text += g.text
+				out[n-1].x1 = math.Max(out[n-1].x1, g.x1)
+				continue
+			}
+			if gap < h {
+				out[n-1].text += " " + g.text
+				out[n-1].x1 = math.Max(out[n-1].x1, g.x1)
+				continue
+			}
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// isDataLine reports whether the line begins a table row, which in the
+// bulletins always starts with a serial number.
+func isDataLine(l line) bool {
+	return len(l.boxes) > 1 && reSerial.MatchString(l.boxes[0].text)
+}
+
+// buildColumns derives the column spans of a table from the boxes of its
+// header lines, merging boxes that overlap horizontally.
+func buildColumns(header []line) []tableColumn {
+	var boxes []box
+	for _, l := range header {
+		boxes = append(boxes, l.boxes...)
+	}
+	sort.SliceStable(boxes, func(i, j int) bool {
+		return boxes[i].x0 < boxes[j].x0
+	})
+	var cols []tableColumn
+	for _, b := range boxes {
+		n := len(cols)
+		if n > 0 && b.x0 < cols[n-1].x1 {
+			cols[n-1].x1 = math.Max(cols[n-1].x1, b.x1)
+			cols[n-1].header = append(cols[n-1].header, b.text)
+			continue
+		}
+		cols = append(cols, tableColumn{x0: b.x0, x1: b.x1, header: []string{b.text}})
+	}
+	return cols
+}
+
+// columnOf returns the index of the column that best fits the box: the one
+// it overlaps most, or failing that the one whose centre is closest.
+func columnOf(cols []tableColumn, b box) int {
+	best, bestOverlap := -1, 0.0
+	for i, c := range cols {
+		o := math.Min(c.x1, b.x1) - math.Max(c.x0, b.x0)
+		if o > bestOverlap {
+			best, bestOverlap = i, o
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	mid := (b.x0 + b.x1) / 2
+	bestDist := math.Inf(1)
+	for i, c := range cols {
+		d := math.Abs((c.x0+c.x1)/2 - mid)
+		if d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// headerStart returns the index of the first header line of the table whose
+// first data line is at i. Lines directly above without a serial number and
+// no further apart than two and a half line heights are taken as header, as
+// long as every box of the line sits over a single column of the lines below
+// it. Once a line naming the district or serial number column is reached,
+// only lines that also name one are taken, so a caption above the header is
+// left for the title.
+func headerStart(lines []line, i int, maxLines int) int {
+	start := i
+	named := false
+	for j := i - 1; j >= 0 && i-j <= maxLines; j-- {
+		l := lines[j]
+		if isDataLine(l) || l.y-lines[j+1].y > 2.5*math.Max(l.h, 1) {
+			break
+		}
+		isHeader := reHeader.MatchString(l.text())
+		if named && !isHeader || !linesUp(l, buildColumns(lines[j+1:i+1])) {
+			break
+		}
+		named = named || isHeader
+		start = j
+	}
+	return start
+}
+
+// linesUp reports whether none of the boxes of l spans more than one of the
+// columns.
+func linesUp(l line, cols []tableColumn) bool {
+	for _, b := range l.boxes {
+		n := 0
+		for _, c := range cols {
+			if math.Min(c.x1, b.x1) > math.Max(c.x0, b.x0) {
+				n++
+			}
+		}
+		if n > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// titleAbove returns the text of the lines above a table header starting at
+// start, up to three lines and stopping at any wide vertical gap or row.
+func titleAbove(lines []line, start int) string {
+	from := start
+	for j := start - 1; j >= 0 && start-j <= 3; j-- {
+		if isDataLine(lines[j]) || lines[j].y-lines[j+1].y > 4*math.Max(lines[j].h, 1) {
+			break
+		}
+		from = j
+	}
+	return linesText(lines[from:start])
+}
+
+// ExtractTables reconstructs the tables of a bulletin pdf from text
+// positions. A table is a header followed by lines starting with a serial
+// number; lines between them without a serial number continue the cells of
+// the previous row. A table continued on the next page without a header of
+// its own keeps the columns of the previous page.
+func ExtractTables(pdf []byte) ([]Table, error) {
+	pages, err := extractLines(pdf)
+	if err != nil {
+		return nil, err
+	}
+	return buildTables(pages), nil
+}
+
+func buildTables(pages [][]line) []Table {
+	var tables []Table
+	var cols []tableColumn
+	var cur *Table
+	closeTable := func() {
+		if cur != nil && len(cur.Rows) > 0 {
+			tables = append(tables, *cur)
+		}
+		cur = nil
+	}
+	// suspended holds a table cut by a page break until the next page shows
+	// whether it carries on.
+	var suspended *Table
+	for _, lines := range pages {
+		if cur != nil {
+			suspended, cur = cur, nil
+		}
+		for i, l := range lines {
+			if isDataLine(l) {
+				if cur == nil {
+					hs := headerStart(lines, i, 4)
+					header := lines[hs:i]
+					if suspended != nil && !reHeader.MatchString(linesText(header)) {
+						cur, suspended = suspended, nil
+					} else {
+						if suspended != nil {
+							cur, suspended = suspended, nil
+							closeTable()
+						}
+						cols = buildColumns(header)
+						if len(cols) < 2 {
+							continue
+						}
+						cur = &Table{Title: titleAbove(lines, hs)}
+						for _, c := range cols {
+							cur.Header = append(cur.Header, strings.Join(c.header, " "))
+						}
+					}
+				}
+				cur.Rows = append(cur.Rows, fillRow(cols, l, make([]string, len(cols))))
+				continue
+			}
+			if cur == nil {
+				continue
+			}
+			prev := lines[i-1]
+			if reTotal.MatchString(l.boxes[0].text) || prev.y-l.y > 2*math.Max(prev.h, l.h) {
+				closeTable()
+				continue
+			}
+			fillRow(cols, l, cur.Rows[len(cur.Rows)-1])
+		}
+	}
+	if cur == nil && suspended != nil {
+		cur = suspended
+	}
+	closeTable()
+	return tables
+}
+
+// fillRow appends the boxes of the line to the cells of row by column.
+func fillRow(cols []tableColumn, l line, row []string) []string {
+	for _, b := range l.boxes {
+		c := columnOf(cols, b)
+		row[c] = strings.TrimSpace(row[c] + " " + b.text)
+	}
+	return row
+}
+
+func linesText(lines []line) string {
+	var s []string
+	for _, l := range lines {
+		s = append(s, l.text())
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,118 @@
+package dhs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lu4p/unipdf/v3/extractor"
+	pdfmodel "github.com/lu4p/unipdf/v3/model"
+)
+
+// cell returns a box of text from x0 to x1 on a line 10 units high.
+func cell(text string, x0, x1 float64) box {
+	return box{text: text, x0: x0, x1: x1, y1: 10}
+}
+
+// at returns a line of the cells with its baseline at y.
+func at(y float64, cells ...box) line {
+	return line{boxes: cells, y: y, h: 10}
+}
+
+func mark(text string, x0, x1, y float64) extractor.TextMark {
+	return extractor.TextMark{Text: text, BBox: pdfmodel.PdfRectangle{Llx: x0, Lly: y, Urx: x1, Ury: y + 10}}
+}
+
+func TestGroupLines(t *testing.T) {
+	marks := []extractor.TextMark{
+		mark("2", 10, 15, 80),
+		mark("1", 10, 15, 100),
+		mark("K", 40, 46, 99.5),
+		mark("o", 46.5, 52, 100),
+		{Text: " ", BBox: pdfmodel.PdfRectangle{Llx: 52, Lly: 100, Urx: 54, Ury: 110}},
+		{Text: "\n", Meta: true},
+		mark("y", 60, 66, 100),
+		mark("M", 40, 48, 80),
+	}
+	var got [][]string
+	for _, l := range groupLines(marks) {
+		var cells []string
+		for _, b := range l.boxes {
+			cells = append(cells, b.text)
+		}
+		got = append(got, cells)
+	}
+	want := [][]string{{"1", "Ko y"}, {"2", "M"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJoinBoxes(t *testing.T) {
+	tests := []struct {
+		name   string
+		glyphs []box
+		want   []box
+	}{
+		{"letters", []box{cell("a", 10, 15), cell("b", 16, 20)}, []box{cell("ab", 10, 20)}},
+		{"words", []box{cell("b", 26, 30), cell("a", 10, 20)}, []box{cell("a b", 10, 30)}},
+		{"cells", []box{cell("a", 10, 20), cell("b", 30, 40)}, []box{cell("a", 10, 20), cell("b", 30, 40)}},
+	}
+	for _, tt := range tests {
+		if got := joinBoxes(tt.glyphs, 10); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTables(t *testing.T) {
+	header := at(185, cell("Sl. No", 10, 40), cell("District", 60, 110), cell("Local Body", 150, 220), cell("Wards", 260, 300))
+	rows := []line{
+		at(170, cell("1", 15, 20), cell("Kollam", 60, 100), cell("Kollam Corporation", 150, 240), cell("5, 6", 260, 280)),
+		at(158, cell("(Part)", 150, 180)),
+		at(145, cell("2", 15, 20), cell("Idukki", 60, 100), cell("Munnar", 150, 190), cell("All", 260, 280)),
+	}
+	total := at(130, cell("Total", 10, 40), cell("2", 260, 265))
+	page := func(lines ...line) []line {
+		return append(append(lines, rows...), total)
+	}
+	table := Table{
+		Header: []string{"Sl. No", "District", "Local Body", "Wards"},
+		Rows: [][]string{
+			{"1", "Kollam", "Kollam Corporation (Part)", "5, 6"},
+			{"2", "Idukki", "Munnar", "All"},
+		},
+	}
+	titled := table
+	titled.Title = "Table 5. New Hotspots"
+	tests := []struct {
+		name  string
+		pages [][]line
+		want  []Table
+	}{
+		{"plain", [][]line{page(header)}, []Table{table}},
+		{"caption", [][]line{page(at(200, cell("Table 5. New Hotspots", 50, 250)), header)}, []Table{titled}},
+		{"caption over one column", [][]line{page(at(200, cell("Table 5. New Hotspots", 10, 40)), header)}, []Table{titled}},
+		{"wrapped header", [][]line{page(
+			at(195, cell("Sl.", 10, 25), cell("District", 60, 110), cell("Local Body", 150, 220), cell("Wards", 260, 300)),
+			at(185, cell("No", 10, 25)),
+		)}, []Table{{
+			Header: []string{"Sl. No", "District", "Local Body", "Wards"},
+			Rows:   table.Rows,
+		}}},
+		{"next page", [][]line{
+			append([]line{header}, rows[0]),
+			{at(700, cell("2", 15, 20), cell("Idukki", 60, 100), cell("Munnar", 150, 190), cell("All", 260, 280))},
+		}, []Table{{
+			Header: table.Header,
+			Rows: [][]string{
+				{"1", "Kollam", "Kollam Corporation", "5, 6"},
+				{"2", "Idukki", "Munnar", "All"},
+			},
+		}}},
+	}
+	for _, tt := range tests {
+		if got := buildTables(tt.pages); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package dhs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	. "scrape/common"
+)
+
+const (
+	// LIBRARY_DIR is where bulletin pdfs and their text are kept, one
+	// directory per date.
+	LIBRARY_DIR = "./bulletins"
+	// RECENT_DAYS is how many days back the DHS may still add posts or
+	// attachments for a date, so held entries of those dates are refreshed.
+	RECENT_DAYS = 2
+	// REFRESH_INTERVAL is how long held entries of a recent date are used
+	// before the date's posts are looked up again.
+	REFRESH_INTERVAL = time.Hour
+)
+
+type LibraryEntry struct {
+	Date       string       `json:"date"`
+	Post       BulletinPost `json:"post"`
+	Attachment Attachment   `json:"attachment"`
+	// File and TextFile are relative to the library directory.
+	File     string    `json:"file"`
+	TextFile string    `json:"text_file"`
+	Fetched  time.Time `json:"fetched"`
+}
+
+// Library is a local store of bulletin pdfs indexed by date, with the
+// extracted text cached alongside so bulletins are downloaded and read once.
+type Library struct {
+	Dir     string
+	entries map[string][]LibraryEntry
+	closed  bool
+	mu      sync.Mutex
+}
+
+type SearchHit struct {
+	Entry LibraryEntry
+	Line  int
+	Text  string
+}
+
+// DefaultLibrary is used by the parsers to reach bulletins.
+var DefaultLibrary = &Library{Dir: LIBRARY_DIR}
+
+func (l *Library) indexFile() string {
+	return filepath.Join(l.Dir, "index.json")
+}
+
+// load reads the index on first use. The caller holds l.mu.
+func (l *Library) load() error {
+	if l.entries != nil {
+		return nil
+	}
+	l.entries = make(map[string][]LibraryEntry)
+	s, err := ioutil.ReadFile(l.indexFile())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []LibraryEntry
+	if err := json.Unmarshal(s, &entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		l.entries[e.Date] = append(l.entries[e.Date], e)
+	}
+	return nil
+}
+
+// save writes the index sorted by date. The caller holds l.mu.
+func (l *Library) save() error {
+	var entries []LibraryEntry
+	for _, d := range l.dates() {
+		entries = append(entries, l.entries[d]...)
+	}
+	j, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(l.Dir, 0755); err != nil {
+		return err
+	}
+	// write to a temporary file and rename it so the index is never left
+	// half written
+	tmp := l.indexFile() + ".tmp"
+	if err := ioutil.WriteFile(tmp, j, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.indexFile())
+}
+
+// Close waits for any index write in progress and stops later fetches from
+// writing the index, so the process can exit without cutting one short.
+func (l *Library) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+}
+
+// dates returns the indexed dates in order. The caller holds l.mu.
+func (l *Library) dates() []string {
+	var dates []string
+	for d := range l.entries {
+		dates = append(dates, d)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return DateBefore(dates[i], dates[j])
+	})
+	return dates
+}
+
+// Entries returns the indexed attachments of every date, oldest first.
+func (l *Library) Entries() ([]LibraryEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	var entries []LibraryEntry
+	for _, d := range l.dates() {
+		entries = append(entries, l.entries[d]...)
+	}
+	return entries, nil
+}
+
+// stale reports whether the held entries of a date should be fetched again:
+// when none of them is in English, or when the date is recent and they
+// haven't been refreshed within REFRESH_INTERVAL.
+func stale(date string, held []LibraryEntry) bool {
+	english := false
+	var fetched time.Time
+	for _, e := range held {
+		english = english || IsEnglish(e.Attachment)
+		if e.Fetched.After(fetched) {
+			fetched = e.Fetched
+		}
+	}
+	if !english {
+		return true
+	}
+	t, err := ParseDate(date)
+	if err != nil {
+		return false
+	}
+	return DaysBetween(t, time.Now()) < RECENT_DAYS && time.Since(fetched) > REFRESH_INTERVAL
+}
+
+// Fetch downloads every attachment of the date's bulletin posts into the
+// library. Dates already held are skipped unless force is set or the held
+// entries are stale, in which case they are kept if the refresh fails.
+func (l *Library) Fetch(date string, force bool) ([]LibraryEntry, error) {
+	l.mu.Lock()
+	if err := l.load(); err != nil {
+		l.mu.Unlock()
+		return nil, err
+	}
+	held, ok := l.entries[date]
+	l.mu.Unlock()
+	if ok && !force && !stale(date, held) {
+		return held, nil
+	}
+	entries, err := l.download(date)
+	if err != nil {
+		if ok {
+			log.Printf("ERROR refreshing bulletins for %v, using the held ones: %v\n", date, err)
+			return held, nil
+		}
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return entries, fmt.Errorf("library closed before indexing %v", date)
+	}
+	l.entries[date] = entries
+	return entries, l.save()
+}
+
+// download fetches every attachment of the date's bulletin posts into the
+// date's directory and returns their entries.
+func (l *Library) download(date string) ([]LibraryEntry, error) {
+	posts, err := FindBulletinPosts(date)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(l.Dir, date)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var entries []LibraryEntry
+	for _, p := range posts {
+		attachments, err := GetAttachments(p.URL)
+		if err != nil {
+			log.Printf("ERROR listing attachments of %v: %v\n", p.URL, err)
+			continue
+		}
+		for _, a := range attachments {
+			log.Printf("retrieving pdf from url: %v", a.URL)
+			d, err := downloadDocument(a)
+			if err != nil {
+				log.Printf("ERROR downloading %v: %v\n", a.URL, err)
+				continue
+			}
+			name := fmt.Sprintf("%v-%v", p.Suffix, path.Base(strings.Split(a.URL, "?")[0]))
+			e := LibraryEntry{
+				Date:       date,
+				Post:       p,
+				Attachment: d.Attachment,
+				File:       filepath.Join(date, name),
+				TextFile:   filepath.Join(date, strings.TrimSuffix(name, filepath.Ext(name))+".txt"),
+				Fetched:    time.Now(),
+			}
+			if err := ioutil.WriteFile(filepath.Join(l.Dir, e.File), d.PDF, 0644); err != nil {
+				return nil, err
+			}
+			if err := ioutil.WriteFile(filepath.Join(l.Dir, e.TextFile), []byte(d.Text), 0644); err != nil {
+				return nil, err
+			}
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("error fetching any bulletin for %v", date)
+	}
+	return entries, nil
+}
+
+// Documents returns the date's attachments accepted by want, main bulletins
+// first, fetching the date into the library if it isn't held yet.
+func (l *Library) Documents(date string, want func(Attachment) bool) ([]Document, error) {
+	entries, err := l.Fetch(date, false)
+	if err != nil {
+		return nil, err
+	}
+	var main, rest []Document
+	for _, e := range entries {
+		if !want(e.Attachment) {
+			continue
+		}
+		d := Document{Attachment: e.Attachment}
+		d.PDF, err = ioutil.ReadFile(filepath.Join(l.Dir, e.File))
+		if err != nil {
+			return nil, err
+		}
+		txt, err := ioutil.ReadFile(filepath.Join(l.Dir, e.TextFile))
+		if err != nil {
+			return nil, err
+		}
+		d.Text = string(txt)
+		if e.Attachment.Kind == KIND_BULLETIN {
+			main = append(main, d)
+		} else {
+			rest = append(rest, d)
+		}
+	}
+	if len(main)+len(rest) == 0 {
+		return nil, fmt.Errorf("error finding a matching pdf for %v in the library", date)
+	}
+	return append(main, rest...), nil
+}
+
+// Text returns the cached text of a library entry.
+func (l *Library) Text(e LibraryEntry) (string, error) {
+	s, err := ioutil.ReadFile(filepath.Join(l.Dir, e.TextFile))
+	return string(s), err
+}
+
+// Search returns every line of the cached bulletin text matching re.
+func (l *Library) Search(re *regexp.Regexp) ([]SearchHit, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var hits []SearchHit
+	for _, e := range entries {
+		txt, err := l.Text(e)
+		if err != nil {
+			log.Printf("ERROR reading %v: %v\n", e.TextFile, err)
+			continue
+		}
+		for i, line := range strings.Split(txt, "\n") {
+			if re.MatchString(line) {
+				hits = append(hits, SearchHit{Entry: e, Line: i + 1, Text: strings.TrimSpace(line)})
+			}
+		}
+	}
+	return hits, nil
+}
@@ -0,0 +1,33 @@
+package dhs
+
+import (
+	"testing"
+	"time"
+
+	. "scrape/common"
+)
+
+func TestStale(t *testing.T) {
+	today := FormatDate(time.Now())
+	english := Attachment{Language: LANG_ENGLISH, Kind: KIND_BULLETIN}
+	malayalam := Attachment{Language: LANG_MALAYALAM, Kind: KIND_BULLETIN}
+	fresh := time.Now()
+	old := time.Now().Add(-2 * REFRESH_INTERVAL)
+	tests := []struct {
+		name string
+		date string
+		held []LibraryEntry
+		want bool
+	}{
+		{"old date", "01-05-2020", []LibraryEntry{{Attachment: english, Fetched: old}}, false},
+		{"no english", "01-05-2020", []LibraryEntry{{Attachment: malayalam, Fetched: old}}, true},
+		{"recent, fetched just now", today, []LibraryEntry{{Attachment: english, Fetched: fresh}}, false},
+		{"recent, fetched a while ago", today, []LibraryEntry{{Attachment: english, Fetched: old}}, true},
+		{"recent, partly refetched", today, []LibraryEntry{{Attachment: english, Fetched: old}, {Attachment: malayalam, Fetched: fresh}}, false},
+	}
+	for _, tt := range tests {
+		if got := stale(tt.date, tt.held); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	. "scrape/common"
+)
+
+// gazetteerCommand prints the LSG records, or writes them as a gazetteer
+// file to be completed with codes and other details. With -date only the
+// records valid on the date are listed.
+func gazetteerCommand(args []string) {
+	fs := flag.NewFlagSet("gazetteer", flag.ExitOnError)
+	district := fs.String("district", "", "only list LSGs of the district")
+	date := fs.String("date", "", "only list LSGs valid on the date (dd-mm-yyyy)")
+	out := fs.String("o", "", "write the records as json to the file")
+	fs.Parse(args)
+	lsgs := make([]LSG, 0)
+	for _, v := range LSGGazetteer.LSGs {
+		if (*district == "" || v.District == *district) && (*date == "" || v.ValidOn(*date)) {
+			lsgs = append(lsgs, v)
+		}
+	}
+	if *out != "" {
+		// indented, as the file is meant to be edited by hand
+		j, err := json.MarshalIndent(lsgs, "", "  ")
+		if err != nil {
+			log.Panicln("ERROR encoding gazetteer", err)
+		}
+		if err := ioutil.WriteFile(*out, append(j, '\n'), 0644); err != nil {
+			log.Panicln("ERROR writing gazetteer", err)
+		}
+		log.Println(len(lsgs), "LSGs written to", *out)
+		return
+	}
+	for _, v := range lsgs {
+		fmt.Fprintf(os.Stdout, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", v.ID, v.Code, v.District, v.Name, v.Type, v.From, v.To)
+	}
+}
@@ -0,0 +1,177 @@
+package geo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "scrape/common"
+)
+
+// Boundary is the boundary, or only the centroid, of an LSG or one of its
+// wards. Ward is 0 for the whole LSG.
+type Boundary struct {
+	LSGID    string
+	District string
+	LSGD     string
+	Ward     int
+	Geometry json.RawMessage
+}
+
+// Boundaries holds the LSG and ward boundaries of a boundary file by LSG ID.
+type Boundaries struct {
+	LSGs  map[string]Boundary
+	Wards map[string]map[int]Boundary
+}
+
+var (
+	idKeys       = []string{"lsg_id"}
+	codeKeys     = []string{"lgd_code", "lsg_code", "code", "lgd"}
+	nameKeys     = []string{"lsgd", "lsg", "lsg_name", "lsgname", "local_body", "localbody", "panchayat", "name"}
+	districtKeys = []string{"district", "dist_name", "district_name", "dist"}
+	wardKeys     = []string{"ward", "ward_no", "ward_number", "wardno"}
+)
+
+// property returns the first of the keys present in the properties,
+// ignoring case, as a string.
+func property(props map[string]interface{}, keys []string) string {
+	for _, key := range keys {
+		for k, v := range props {
+			if !strings.EqualFold(k, key) || v == nil {
+				continue
+			}
+			switch v := v.(type) {
+			case string:
+				return strings.TrimSpace(v)
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	}
+	return ""
+}
+
+// resolve finds the LSG a boundary feature belongs to, by its LSG ID, its
+// LGD code or its district and name in that order.
+func resolve(props map[string]interface{}) (LSG, bool) {
+	if id := property(props, idKeys); id != "" {
+		if l, ok := LSGGazetteer.ByID(id); ok {
+			return l, true
+		}
+	}
+	if code := property(props, codeKeys); code != "" {
+		if l, ok := LSGGazetteer.ByCode(code); ok {
+			return l, true
+		}
+	}
+	name, district := property(props, nameKeys), property(props, districtKeys)
+	if name == "" || district == "" {
+		return LSG{}, false
+	}
+	d := MatchDistrict(district)
+	if d.Score < 80 {
+		return LSG{}, false
+	}
+	m := MatchLSG(d.Match, name, "")
+	if m.Score < REVIEW_THRESHOLD {
+		return LSG{}, false
+	}
+	return LSGGazetteer.Lookup(d.Match, m.Match)
+}
+
+// ReadBoundaries reads a GeoJSON file of LSG or ward boundaries. Features
+// are matched to LSGs through their properties; those that can't be are
+// logged and skipped.
+func ReadBoundaries(filename string) (*Boundaries, error) {
+	s, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var fc struct {
+		Features []struct {
+			Geometry   json.RawMessage        `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(s, &fc); err != nil {
+		return nil, err
+	}
+	b := &Boundaries{LSGs: make(map[string]Boundary), Wards: make(map[string]map[int]Boundary)}
+	unmatched := 0
+	for _, f := range fc.Features {
+		l, ok := resolve(f.Properties)
+		if !ok || len(f.Geometry) == 0 || string(f.Geometry) == "null" {
+			unmatched++
+			continue
+		}
+		v := Boundary{LSGID: l.ID, District: l.District, LSGD: l.GeoName, Geometry: f.Geometry}
+		if w := property(f.Properties, wardKeys); w != "" {
+			n, err := strconv.Atoi(w)
+			if err != nil {
+				unmatched++
+				continue
+			}
+			v.Ward = n
+			if b.Wards[l.ID] == nil {
+				b.Wards[l.ID] = make(map[int]Boundary)
+			}
+			b.Wards[l.ID][n] = v
+			continue
+		}
+		b.LSGs[l.ID] = v
+	}
+	if unmatched > 0 {
+		log.Printf("%v features of %v matched no LSG\n", unmatched, filename)
+	}
+	return b, nil
+}
+
+// Geometry returns the geometry covering the wards of the LSG: all of them
+// but those in except when all is set, or the wards listed, all of them
+// when none are. The LSG's own boundary is used for the whole LSG, and the
+// wards' boundaries merged otherwise or when the LSG has none. It reports
+// "lsg", "wards" or "" for no geometry.
+func (b *Boundaries) Geometry(lsgID string, wards []int, all bool, except []int) (json.RawMessage, string) {
+	whole := len(wards) == 0
+	if all {
+		whole = len(except) == 0
+	}
+	if v, ok := b.LSGs[lsgID]; ok && whole {
+		return v.Geometry, "lsg"
+	}
+	var numbers []int
+	for n := range b.Wards[lsgID] {
+		if whole || (all && !containsInt(except, n)) || (!all && containsInt(wards, n)) {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	var geoms []json.RawMessage
+	for _, n := range numbers {
+		geoms = append(geoms, b.Wards[lsgID][n].Geometry)
+	}
+	if len(geoms) == 0 {
+		if v, ok := b.LSGs[lsgID]; ok {
+			return v.Geometry, "lsg"
+		}
+		return nil, ""
+	}
+	g, err := Merge(geoms)
+	if err != nil {
+		log.Println("error merging ward boundaries of", lsgID, err)
+		return nil, ""
+	}
+	return g, "wards"
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package geo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// Feature is a GeoJSON feature. The geometry is kept as it was read.
+type Feature struct {
+	Type       string          `json:"type"`
+	Geometry   json.RawMessage `json:"geometry"`
+	Properties interface{}     `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+func NewFeature(geometry json.RawMessage, properties interface{}) Feature {
+	if len(geometry) == 0 {
+		geometry = json.RawMessage("null")
+	}
+	return Feature{Type: "Feature", Geometry: geometry, Properties: properties}
+}
+
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = make([]Feature, 0)
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// ReadFeatureCollection reads a GeoJSON file, returning an empty collection
+// when it doesn't exist.
+func ReadFeatureCollection(filename string) (FeatureCollection, error) {
+	fc := NewFeatureCollection(nil)
+	s, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return fc, nil
+	}
+	if err != nil {
+		return fc, err
+	}
+	err = json.Unmarshal(s, &fc)
+	return fc, err
+}
+
+// Properties flattens the JSON fields of v into props, each name prefixed
+// with prefix.
+func Properties(props map[string]interface{}, prefix string, v interface{}) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		props[prefix+k] = v
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Point is a position as longitude and latitude.
+type Point [2]float64
+
+// Polygon is a list of rings, the outer ring first and then its holes.
+type Polygon [][]Point
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// decodeGeometry reads the polygons or the points of a geometry. Other
+// geometry types are an error.
+func decodeGeometry(raw json.RawMessage) ([]Polygon, []Point, error) {
+	var g geometry
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, nil, err
+	}
+	switch g.Type {
+	case "Polygon":
+		var p Polygon
+		err := json.Unmarshal(g.Coordinates, &p)
+		return []Polygon{p}, nil, err
+	case "MultiPolygon":
+		var mp []Polygon
+		err := json.Unmarshal(g.Coordinates, &mp)
+		return mp, nil, err
+	case "Point":
+		var p Point
+		err := json.Unmarshal(g.Coordinates, &p)
+		return nil, []Point{p}, err
+	case "MultiPoint":
+		var mp []Point
+		err := json.Unmarshal(g.Coordinates, &mp)
+		return nil, mp, err
+	}
+	return nil, nil, fmt.Errorf("unsupported geometry %q", g.Type)
+}
+
+// Merge combines geometries into one MultiPolygon, or a MultiPoint when
+// none of them has polygons.
+func Merge(geoms []json.RawMessage) (json.RawMessage, error) {
+	var polygons []Polygon
+	var points []Point
+	for _, raw := range geoms {
+		pg, pt, err := decodeGeometry(raw)
+		if err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, pg...)
+		points = append(points, pt...)
+	}
+	if len(polygons) > 0 {
+		return json.Marshal(struct {
+			Type        string    `json:"type"`
+			Coordinates []Polygon `json:"coordinates"`
+		}{"MultiPolygon", polygons})
+	}
+	return json.Marshal(struct {
+		Type        string  `json:"type"`
+		Coordinates []Point `json:"coordinates"`
+	}{"MultiPoint", points})
+}
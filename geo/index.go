@@ -0,0 +1,173 @@
+package geo
+
+import (
+	"math"
+
+	"scrape/scraper"
+)
+
+// CELL_SIZE is the side in degrees of the grid cells of an Index.
+const CELL_SIZE = 0.05
+
+type cell [2]int
+
+type indexEntry struct {
+	boundary Boundary
+	polygons []Polygon
+}
+
+// Index finds the LSG and ward boundaries containing a point. Boundaries
+// are filed under every grid cell their bounding box overlaps, so a lookup
+// only tests the polygons of one cell. Boundaries that are only points
+// can't contain anything and are left out.
+type Index struct {
+	lsgs  map[cell][]indexEntry
+	wards map[cell][]indexEntry
+}
+
+func cellOf(p Point) cell {
+	return cell{int(math.Floor(p[0] / CELL_SIZE)), int(math.Floor(p[1] / CELL_SIZE))}
+}
+
+func add(grid map[cell][]indexEntry, b Boundary) {
+	polygons, _, err := decodeGeometry(b.Geometry)
+	if err != nil || len(polygons) == 0 {
+		return
+	}
+	min, max := Point{math.Inf(1), math.Inf(1)}, Point{math.Inf(-1), math.Inf(-1)}
+	for _, pg := range polygons {
+		if len(pg) == 0 {
+			continue
+		}
+		for _, p := range pg[0] {
+			min = Point{math.Min(min[0], p[0]), math.Min(min[1], p[1])}
+			max = Point{math.Max(max[0], p[0]), math.Max(max[1], p[1])}
+		}
+	}
+	if math.IsInf(min[0], 1) {
+		return
+	}
+	e := indexEntry{boundary: b, polygons: polygons}
+	lo, hi := cellOf(min), cellOf(max)
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			grid[cell{x, y}] = append(grid[cell{x, y}], e)
+		}
+	}
+}
+
+// NewIndex indexes the polygons of the boundaries.
+func NewIndex(b *Boundaries) *Index {
+	ix := &Index{lsgs: make(map[cell][]indexEntry), wards: make(map[cell][]indexEntry)}
+	for _, v := range b.LSGs {
+		add(ix.lsgs, v)
+	}
+	for _, wards := range b.Wards {
+		for _, v := range wards {
+			add(ix.wards, v)
+		}
+	}
+	return ix
+}
+
+// inRing tests the point against a ring by casting a ray to the east.
+func inRing(p Point, ring []Point) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// contains reports whether the point lies in any of the polygons, inside
+// the outer ring and outside every hole.
+func contains(polygons []Polygon, p Point) bool {
+	for _, pg := range polygons {
+		if len(pg) == 0 || !inRing(p, pg[0]) {
+			continue
+		}
+		hole := false
+		for _, r := range pg[1:] {
+			if inRing(p, r) {
+				hole = true
+				break
+			}
+		}
+		if !hole {
+			return true
+		}
+	}
+	return false
+}
+
+func find(grid map[cell][]indexEntry, p Point) (Boundary, bool) {
+	for _, e := range grid[cellOf(p)] {
+		if contains(e.polygons, p) {
+			return e.boundary, true
+		}
+	}
+	return Boundary{}, false
+}
+
+// Locate returns the LSG containing the point and, when ward boundaries
+// are known, its ward. The LSG is taken from the ward when the LSG itself
+// has no boundary.
+func (ix *Index) Locate(p Point) (lsg Boundary, ward Boundary, ok bool) {
+	ward, wok := find(ix.wards, p)
+	lsg, ok = find(ix.lsgs, p)
+	if !ok && wok {
+		lsg, ok = ward, true
+		lsg.Ward = 0
+	}
+	if wok && ok && ward.LSGID != lsg.LSGID {
+		wok = false
+	}
+	if !wok {
+		ward = Boundary{}
+	}
+	return lsg, ward, ok
+}
+
+// Location tells where a point lies and whether it is in a hotspot of the
+// list of Date. Hotspot is set when the LSG is listed and either the whole
+// LSG is or the point's ward is among the wards listed. When the ward isn't
+// known and only some wards are listed, Hotspot is false and LSGListed
+// true.
+type Location struct {
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	Found     bool    `json:"found"`
+	District  string  `json:"district,omitempty"`
+	LSGD      string  `json:"lsgd,omitempty"`
+	LSGID     string  `json:"lsg_id,omitempty"`
+	Ward      int     `json:"ward,omitempty"`
+	Hotspot   bool    `json:"hotspot"`
+	LSGListed bool    `json:"lsg_listed"`
+	Wards     string  `json:"wards,omitempty"`
+	Date      string  `json:"date"`
+}
+
+// Lookup locates the point and checks it against the hotspot list.
+func (ix *Index) Lookup(lat float64, lon float64, hh scraper.HotspotsHistory) Location {
+	loc := Location{Lat: lat, Lon: lon, Date: hh.Date}
+	lsg, ward, ok := ix.Locate(Point{lon, lat})
+	if !ok {
+		return loc
+	}
+	loc.Found = true
+	loc.District, loc.LSGD, loc.LSGID, loc.Ward = lsg.District, lsg.LSGD, lsg.LSGID, ward.Ward
+	h, listed := hh.Find(lsg.District, lsg.LSGD)
+	if !listed {
+		return loc
+	}
+	loc.LSGListed, loc.Wards = true, h.Wards
+	if loc.Ward > 0 {
+		loc.Hotspot = h.HasWard(loc.Ward)
+	} else {
+		loc.Hotspot = h.WholeLSG()
+	}
+	return loc
+}
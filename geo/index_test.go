@@ -0,0 +1,60 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"scrape/scraper"
+)
+
+// square returns a polygon geometry spanning lon0..lon1 and lat0..lat1.
+func square(lon0, lat0, lon1, lat1 float64) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"type":"Polygon","coordinates":[[[%v,%v],[%v,%v],[%v,%v],[%v,%v],[%v,%v]]]}`,
+		lon0, lat0, lon1, lat0, lon1, lat1, lon0, lat1, lon0, lat0))
+}
+
+func TestLookup(t *testing.T) {
+	munnar := Boundary{LSGID: "idk-munnar", District: "Idukki", LSGD: "Munnar"}
+	b := &Boundaries{
+		LSGs: map[string]Boundary{
+			"idk-munnar": {LSGID: munnar.LSGID, District: munnar.District, LSGD: munnar.LSGD, Geometry: square(77.0, 10.0, 77.2, 10.1)},
+		},
+		Wards: map[string]map[int]Boundary{
+			"idk-munnar": {
+				1: {LSGID: munnar.LSGID, District: munnar.District, LSGD: munnar.LSGD, Ward: 1, Geometry: square(77.0, 10.0, 77.05, 10.1)},
+				2: {LSGID: munnar.LSGID, District: munnar.District, LSGD: munnar.LSGD, Ward: 2, Geometry: square(77.05, 10.0, 77.1, 10.1)},
+			},
+			// Chavara has ward boundaries but no boundary of its own
+			"klm-chavara": {
+				3: {LSGID: "klm-chavara", District: "Kollam", LSGD: "Chavara", Ward: 3, Geometry: square(76.5, 9.0, 76.6, 9.1)},
+			},
+		},
+	}
+	ix := NewIndex(b)
+	list := func(hotspots ...scraper.Hotspots) scraper.HotspotsHistory {
+		return scraper.HotspotsHistory{Hotspots: hotspots, Date: "01-05-2020"}
+	}
+	munnarWards := func(wards string) scraper.Hotspots {
+		return scraper.NewHotspots("Idukki", "Munnar", wards, "test", "01-05-2020")
+	}
+	tests := []struct {
+		name     string
+		lat, lon float64
+		hh       scraper.HotspotsHistory
+		want     Location
+	}{
+		{"outside", 11.0, 76.0, list(munnarWards("1")), Location{Lat: 11.0, Lon: 76.0, Date: "01-05-2020"}},
+		{"listed ward", 10.05, 77.02, list(munnarWards("1")), Location{Lat: 10.05, Lon: 77.02, Found: true, District: "Idukki", LSGD: "Munnar", LSGID: "idk-munnar", Ward: 1, Hotspot: true, LSGListed: true, Wards: "1", Date: "01-05-2020"}},
+		{"other ward", 10.05, 77.07, list(munnarWards("1")), Location{Lat: 10.05, Lon: 77.07, Found: true, District: "Idukki", LSGD: "Munnar", LSGID: "idk-munnar", Ward: 2, LSGListed: true, Wards: "1", Date: "01-05-2020"}},
+		{"not listed", 10.05, 77.02, list(), Location{Lat: 10.05, Lon: 77.02, Found: true, District: "Idukki", LSGD: "Munnar", LSGID: "idk-munnar", Ward: 1, Date: "01-05-2020"}},
+		{"unknown ward, some wards", 10.05, 77.15, list(munnarWards("1")), Location{Lat: 10.05, Lon: 77.15, Found: true, District: "Idukki", LSGD: "Munnar", LSGID: "idk-munnar", LSGListed: true, Wards: "1", Date: "01-05-2020"}},
+		{"unknown ward, whole LSG", 10.05, 77.15, list(munnarWards("All wards")), Location{Lat: 10.05, Lon: 77.15, Found: true, District: "Idukki", LSGD: "Munnar", LSGID: "idk-munnar", Hotspot: true, LSGListed: true, Wards: "All wards", Date: "01-05-2020"}},
+		{"ward only", 9.05, 76.55, list(scraper.NewHotspots("Kollam", "Chavara", "3", "test", "01-05-2020")), Location{Lat: 9.05, Lon: 76.55, Found: true, District: "Kollam", LSGD: "Chavara", LSGID: "klm-chavara", Ward: 3, Hotspot: true, LSGListed: true, Wards: "3", Date: "01-05-2020"}},
+	}
+	for _, tt := range tests {
+		if got := ix.Lookup(tt.lat, tt.lon, tt.hh); got != tt.want {
+			t.Errorf("%v: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
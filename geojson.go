@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	. "scrape/common"
+	"scrape/geo"
+	"scrape/scraper"
+)
+
+// districtGeometries returns the geometry of each district, from the
+// dashboard's map when it was scraped and otherwise from the last
+// districts.geojson written.
+func districtGeometries(districts []scraper.GeoDistrict) map[string]json.RawMessage {
+	geometries := make(map[string]json.RawMessage)
+	for _, d := range districts {
+		geometries[d.District] = d.Geometry
+	}
+	if len(geometries) > 0 {
+		return geometries
+	}
+	fc, err := geo.ReadFeatureCollection(DISTRICTS_GEOJSON_FILE)
+	if err != nil {
+		log.Println("ERROR reading", DISTRICTS_GEOJSON_FILE, err)
+		return geometries
+	}
+	for _, f := range fc.Features {
+		if p, ok := f.Properties.(map[string]interface{}); ok {
+			if d, ok := p["district"].(string); ok {
+				geometries[d] = f.Geometry
+			}
+		}
+	}
+	return geometries
+}
+
+// writeDistrictsGeoJSON publishes every district's geometry with its
+// counts and their deltas as properties, the deltas prefixed with "delta_".
+func writeDistrictsGeoJSON(h scraper.History, districts []scraper.GeoDistrict) {
+	geometries := districtGeometries(districts)
+	if len(geometries) == 0 {
+		log.Println("no district geometries, districts geojson not written")
+		return
+	}
+	var features []geo.Feature
+	for _, d := range DistrictList {
+		props := map[string]interface{}{"district": d, "date": h.Date, "last_updated": lastUpdated}
+		if h.DeltaDays > 0 {
+			props["delta_days"] = h.DeltaDays
+		}
+		if err := geo.Properties(props, "", h.Summary[d]); err != nil {
+			log.Panicln(err)
+		}
+		if err := geo.Properties(props, "delta_", h.Delta[d]); err != nil {
+			log.Panicln(err)
+		}
+		features = append(features, geo.NewFeature(geometries[d], props))
+	}
+	WriteJSON(geo.NewFeatureCollection(features), DISTRICTS_GEOJSON_FILE)
+	log.Println("districts geojson written")
+}
+
+// writeHotspotsGeoJSON publishes the hotspots of the list with the boundary
+// of their LSG, or of their wards when only those are known, from the local
+// boundary file. Hotspots without a boundary keep a null geometry.
+func writeHotspotsGeoJSON(hh scraper.HotspotsHistory, t scraper.Timelines) {
+	boundaries, err := geo.ReadBoundaries(LSG_BOUNDARIES_FILE)
+	if os.IsNotExist(err) {
+		log.Println("no LSG boundary file, hotspots geojson not written")
+		return
+	}
+	if err != nil {
+		log.Panicln("ERROR reading LSG boundaries", err)
+	}
+	timelines := make(map[string]scraper.HotspotTimeline)
+	for _, v := range t.Timelines {
+		timelines[v.Key()] = v
+	}
+	features := make([]geo.Feature, 0)
+	missing := 0
+	for _, h := range hh.Hotspots {
+		id := h.LSGID
+		if id == "" {
+			id = LSGGazetteer.LSGID(h.District, h.LSGD, hh.Date)
+		}
+		wards, all, except := h.WardList, h.AllWards, h.ExceptWards
+		if h.Wards != "" {
+			wards, all, except = ParseWards(h.Wards)
+		}
+		g, kind := boundaries.Geometry(id, wards, all, except)
+		if kind == "" {
+			missing++
+		}
+		props := map[string]interface{}{
+			"district":     h.District,
+			"lsgd":         h.LSGD,
+			"lsg_id":       id,
+			"wards":        h.Wards,
+			"ward_list":    wards,
+			"all_wards":    all,
+			"except_wards": except,
+			"sources":      h.Sources,
+			"boundary":     kind,
+			"date":         hh.Date,
+		}
+		key := scraper.HotspotTimeline{District: h.District, LSGD: h.LSGD, LSGID: id}.Key()
+		if tl, ok := timelines[key]; ok {
+			props["days_in_containment"] = tl.CurrentStreak
+			props["total_days"] = tl.TotalDays
+			props["first_seen"] = tl.FirstSeen
+		}
+		features = append(features, geo.NewFeature(g, props))
+	}
+	if missing > 0 {
+		log.Printf("%v hotspots have no boundary\n", missing)
+	}
+	WriteJSON(geo.NewFeatureCollection(features), HOTSPOTS_GEOJSON_FILE)
+	log.Println("hotspots geojson written")
+}
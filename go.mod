@@ -6,5 +6,5 @@ require (
 	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/dlclark/regexp2 v1.4.0
 	github.com/lu4p/cat v0.1.2
-	github.com/paul-mannino/go-fuzzywuzzy v0.0.0-20200127021948-54652b135d0e
+	github.com/lu4p/unipdf/v3 v3.5.1-0.20200321134028-22d47cef6c06
 )
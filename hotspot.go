@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	. "scrape/common"
+	"scrape/scraper"
+)
+
+// lsgDistricts returns the matched district, or every district when none
+// is given.
+func lsgDistricts(district string) []string {
+	if district == "" {
+		return DistrictList
+	}
+	return []string{MatchDistrict(district).Match}
+}
+
+// resolveLSG matches the LSG name within the district, or within every
+// district when none is given, against the LSGs valid on the date and
+// returns the best match with its score.
+func resolveLSG(district string, lsg string, date string) (string, string, int) {
+	var bestDistrict, bestLSG string
+	bestScore := -1
+	for _, d := range lsgDistricts(district) {
+		m := MatchLSG(d, lsg, date)
+		if m.Score > bestScore {
+			bestDistrict, bestLSG, bestScore = d, m.Match, m.Score
+		}
+	}
+	return bestDistrict, bestLSG, bestScore
+}
+
+// closestLSGs lists the k LSGs valid on the date that best match the name
+// with their districts and scores, best first.
+func closestLSGs(district string, lsg string, date string, k int) []string {
+	type candidate struct {
+		district string
+		m        Match
+	}
+	var candidates []candidate
+	for _, d := range lsgDistricts(district) {
+		for _, m := range LSGGazetteer.Match(d, lsg, date, k) {
+			candidates = append(candidates, candidate{d, m})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].m.Score > candidates[j].m.Score
+	})
+	var out []string
+	for i := 0; i < len(candidates) && i < k; i++ {
+		c := candidates[i]
+		out = append(out, fmt.Sprintf("%v, %v (%v)", c.m.Match, c.district, c.m.Score))
+	}
+	return out
+}
+
+func hotspotCommand(args []string) {
+	fs := flag.NewFlagSet("hotspot", flag.ExitOnError)
+	date := fs.String("date", "", "date of the hotspot list (dd-mm-yyyy), the latest by default")
+	district := fs.String("district", "", "district of the LSG")
+	ward := fs.Int("ward", 0, "ward number to look up, any ward when 0")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: scrape hotspot [-date dd-mm-yyyy] [-district name] [-ward n] lsg")
+		os.Exit(2)
+	}
+	var hhistories HotspotsHistories
+	ReadJSON(HOTSPOT_HISTORIES_FILE, &hhistories)
+	var hh *scraper.HotspotsHistory
+	for i := len(hhistories.History) - 1; i >= 0; i-- {
+		if *date == "" || hhistories.History[i].Date == *date {
+			hh = &hhistories.History[i]
+			break
+		}
+	}
+	if hh == nil {
+		log.Panicln("ERROR no hotspot list for", *date)
+	}
+	d, lsg, score := resolveLSG(*district, fs.Arg(0), hh.Date)
+	if score < REVIEW_THRESHOLD {
+		closest := strings.Join(closestLSGs(*district, fs.Arg(0), hh.Date, 5), "; ")
+		log.Panicln(fmt.Sprintf("ERROR no LSG matching %q on %v, closest: %v", fs.Arg(0), hh.Date, closest))
+	}
+	h, ok := hh.Find(d, lsg)
+	switch {
+	case !ok:
+		fmt.Printf("%v, %v is not a hotspot on %v\n", lsg, d, hh.Date)
+	case *ward == 0 || h.HasWard(*ward):
+		fmt.Printf("%v, %v is a hotspot on %v, wards: %v\n", lsg, d, hh.Date, h.Wards)
+	default:
+		fmt.Printf("ward %v of %v, %v is not a hotspot on %v, wards: %v\n", *ward, lsg, d, hh.Date, h.Wards)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"net/http"
 	"os"
 	"strings"
@@ -13,6 +15,7 @@ import (
 	"log"
 
 	. "scrape/common"
+	"scrape/dhs"
 	"scrape/scraper"
 	"scrape/zones"
 )
@@ -24,8 +27,18 @@ const (
 	TEST_REPORTS_FILE      = "./testreports.json"
 	HOTSPOT_HISTORIES_FILE = "./hotspots_histories.json"
 	HOTSPOT_LATEST_FILE    = "./hotspots.json"
+	DISTRICTS_GEOJSON_FILE = "./districts.geojson"
+	HOTSPOTS_GEOJSON_FILE  = "./hotspots.geojson"
+	LSG_BOUNDARIES_FILE    = "./lsg_boundaries.geojson"
+	HOTSPOT_CHANGES_FILE   = "./hotspots_changes.json"
+	HOTSPOT_TIMELINES_FILE = "./hotspots_timelines.json"
+	LSG_ALIASES_FILE       = "./lsg_aliases.json"
+	GAZETTEER_FILE         = "./gazetteer.json"
+	REVIEW_QUEUE_FILE      = "./review_queue.json"
 	ZONES_HISTORIES_FILE   = "./zones_histories.json"
 	ZONES_LATEST_FILE      = "./zones.json"
+	// BULLETIN_TIMEOUT bounds how long a run waits for the day's bulletin.
+	BULLETIN_TIMEOUT = 3 * time.Minute
 )
 
 type Histories struct {
@@ -38,15 +51,34 @@ type HotspotsHistories struct {
 	LastUpdated string                    `json:"last_updated"`
 }
 
+type HotspotsChangesHistories struct {
+	History     []scraper.HotspotsChanges `json:"histories"`
+	LastUpdated string                    `json:"last_updated"`
+}
+
+type HotspotsTimelines struct {
+	Timelines []scraper.HotspotTimeline `json:"timelines"`
+	Through   string                    `json:"through"`
+	Previous  string                    `json:"previous,omitempty"`
+	// Fingerprint identifies the dates of the lists the timelines were
+	// built from.
+	Fingerprint string `json:"fingerprint"`
+	LastUpdated string `json:"last_updated"`
+}
+
 type ZoneHistories struct {
 	History     []zones.Zones `json:"histories"`
 	LastUpdated string        `json:"last_updated"`
 }
 
 type LatestHistory struct {
-	Summary     map[string]scraper.DistrictInfo `json:"summary"`
-	Delta       map[string]scraper.DistrictInfo `json:"delta"`
-	LastUpdated string                          `json:"last_updated"`
+	Summary   map[string]scraper.DistrictInfo `json:"summary"`
+	Delta     map[string]scraper.DistrictInfo `json:"delta"`
+	DeltaDays int                             `json:"delta_days,omitempty"`
+	// Unassigned holds the counts of the state total not in any district.
+	Unassigned      *scraper.DistrictInfo `json:"unassigned,omitempty"`
+	UnassignedDelta *scraper.DistrictInfo `json:"unassigned_delta,omitempty"`
+	LastUpdated     string                `json:"last_updated"`
 }
 
 type LatestHotspotsHistory struct {
@@ -62,6 +94,7 @@ type LatestZones struct {
 type Summary struct {
 	Summary     scraper.DistrictInfo `json:"summary"`
 	Delta       scraper.DistrictInfo `json:"delta"`
+	DeltaDays   int                  `json:"delta_days,omitempty"`
 	LastUpdated string               `json:"last_updated"`
 }
 
@@ -74,6 +107,11 @@ var (
 	date        string
 	lastUpdated string
 	wg          sync.WaitGroup
+
+	bulletin         dhs.Bulletin
+	bulletinErr      error
+	bulletinDone     = make(chan struct{})
+	bulletinDeadline time.Time
 )
 
 func sendWebhook(msg string) {
@@ -121,33 +159,73 @@ func handleHistories() {
 	ReadJSON(HISTORIES_FILE, &histories)
 	last := len(histories.History) - 1
 	var b scraper.History
+	geo, err := scraper.ScrapeGeoJSON()
+	if err != nil {
+		log.Println("ERROR scraping geojson", err)
+	}
 	if date == histories.History[last].Date {
-		b, err = scraper.ScrapeTodaysHistory(date, histories.History[last-1])
+		b, err = scrapeHistory(histories.History[last-1], geo)
 		if err != nil {
 			log.Panicln("ERROR scraping todays history", err)
 			return
 		}
+		if span := scraper.DeltaSpan(histories.History[:last], date); span > 1 {
+			b.DeltaDays = span
+		}
 		histories.History[last] = b
 		log.Println("history replaced")
 	} else {
-		b, err = scraper.ScrapeTodaysHistory(date, histories.History[last])
+		b, err = scrapeHistory(histories.History[last], geo)
 		if err != nil {
 			log.Panicln("ERROR scraping todays history", err)
 			return
 		}
+		var missing []scraper.History
+		missing, err = scraper.Placeholders(histories.History[last], date)
+		if err != nil {
+			log.Panicln("ERROR detecting history gaps", err)
+			return
+		}
+		if span := scraper.DeltaSpan(histories.History, date); span > 1 {
+			b.DeltaDays = span
+		}
+		if len(missing) > 0 {
+			log.Printf("history gap detected, inserting %v missing days after %v\n", len(missing), histories.History[last].Date)
+			histories.History = append(histories.History, missing...)
+		}
 		histories.History = append(histories.History, b)
 		log.Println("history appended")
 	}
 	histories.LastUpdated = lastUpdated
 	WriteJSON(histories, HISTORIES_FILE)
 	log.Println("histories written")
-	latestData := LatestHistory{Summary: b.Summary, Delta: b.Delta, LastUpdated: lastUpdated}
+	latestData := LatestHistory{Summary: b.Summary, Delta: b.Delta, DeltaDays: b.DeltaDays, Unassigned: b.Unassigned, UnassignedDelta: b.UnassignedDelta, LastUpdated: lastUpdated}
 	WriteJSON(latestData, LATEST_FILE)
 	s, d := scraper.LatestSummary(b)
 	log.Println("latest written")
-	summary := Summary{Summary: s, Delta: d, LastUpdated: lastUpdated}
+	summary := Summary{Summary: s, Delta: d, DeltaDays: b.DeltaDays, LastUpdated: lastUpdated}
 	WriteJSON(summary, SUMMARY_FILE)
 	log.Println("summary written")
+	writeDistrictsGeoJSON(b, geo)
+}
+
+// scrapeHistory scrapes the day's history from the dashboard, falling back
+// to the district tables of the DHS bulletin when that fails.
+func scrapeHistory(last scraper.History, geo []scraper.GeoDistrict) (scraper.History, error) {
+	b, err := scraper.ScrapeTodaysHistory(date, last, geo)
+	if err == nil {
+		return b, nil
+	}
+	log.Println("ERROR scraping todays history, trying the bulletin", err)
+	db, berr := todaysBulletin()
+	if berr == nil {
+		var h scraper.History
+		if h, berr = db.History(last); berr == nil {
+			return h, nil
+		}
+	}
+	log.Println("ERROR getting the history from the bulletin", berr)
+	return b, err
 }
 
 func handleTestReports() {
@@ -192,9 +270,19 @@ func handleHotspotsHistories() {
 	ReadJSON(HOTSPOT_HISTORIES_FILE, &hhistories)
 	last := len(hhistories.History) - 1
 	hh, err := scraper.ScrapeHotspotsHistory(date)
+	db, berr := todaysBulletin()
+	if berr != nil {
+		log.Println("ERROR parsing bulletin hotspots", berr)
+	}
 	if err != nil {
-		log.Panicln("ERROR getting hotspots histories", err)
-		return
+		if berr != nil || len(db.Hotspots.Hotspots) == 0 {
+			log.Panicln("ERROR getting hotspots histories", err)
+			return
+		}
+		log.Println("ERROR getting hotspots histories, using the bulletin instead", err)
+		hh = db.Hotspots.ToHistory()
+	} else if berr == nil {
+		crossCheckHotspots(&hh, db.Hotspots)
 	}
 	if date == hhistories.History[last].Date {
 		hhistories.History[last] = hh
@@ -209,6 +297,150 @@ func handleHotspotsHistories() {
 	latestHotspotData := LatestHotspotsHistory{Hotspots: hh.Hotspots, LastUpdated: lastUpdated}
 	WriteJSON(latestHotspotData, HOTSPOT_LATEST_FILE)
 	log.Println("hotspots latest written")
+	writeHotspotsChanges(hhistories.History)
+	log.Println("hotspots changes written")
+	t := writeHotspotsTimelines(hhistories.History)
+	log.Println("hotspots timelines written")
+	writeHotspotsGeoJSON(hh, t)
+}
+
+// writeHotspotsChanges updates the changes of the latest hotspot list. The
+// whole changes history is rebuilt when the file doesn't exist yet or its
+// entries aren't those of the lists before the latest, as after a backfill.
+func writeHotspotsChanges(h []scraper.HotspotsHistory) {
+	changes := HotspotsChangesHistories{History: make([]scraper.HotspotsChanges, 0)}
+	if _, err := os.Stat(HOTSPOT_CHANGES_FILE); err == nil {
+		ReadJSON(HOTSPOT_CHANGES_FILE, &changes)
+	}
+	if n := len(h); n > 1 && changesMatch(changes.History, h) {
+		changes.History = append(changes.History[:n-2], scraper.DiffHotspots(h[n-2], h[n-1]))
+	} else {
+		changes.History = make([]scraper.HotspotsChanges, 0)
+		for i := 1; i < len(h); i++ {
+			changes.History = append(changes.History, scraper.DiffHotspots(h[i-1], h[i]))
+		}
+		log.Println("hotspots changes rebuilt")
+	}
+	changes.LastUpdated = lastUpdated
+	WriteJSON(changes, HOTSPOT_CHANGES_FILE)
+}
+
+// changesMatch reports whether the changes hold an entry for every pair of
+// consecutive lists up to the one before the latest, and at most one more
+// for the latest list.
+func changesMatch(changes []scraper.HotspotsChanges, h []scraper.HotspotsHistory) bool {
+	n := len(h)
+	if len(changes) != n-2 && len(changes) != n-1 {
+		return false
+	}
+	for i := 0; i < n-2; i++ {
+		if changes[i].Since != h[i].Date || changes[i].Date != h[i+1].Date {
+			return false
+		}
+	}
+	return len(changes) == n-2 || changes[n-2].Date == h[n-1].Date
+}
+
+// fetchBulletin starts parsing the day's DHS bulletin in the background
+// for the handlers that fall back to or cross-check with it. The hotspot
+// lists, district tables and test report all come from this one parse.
+func fetchBulletin() {
+	bulletinDeadline = time.Now().Add(BULLETIN_TIMEOUT)
+	go func() {
+		defer close(bulletinDone)
+		bulletin, bulletinErr = parseBulletin(date)
+	}()
+}
+
+// todaysBulletin waits for the day's bulletin until BULLETIN_TIMEOUT after
+// it was started, so a stalled DHS site doesn't hold up the run.
+func todaysBulletin() (dhs.Bulletin, error) {
+	select {
+	case <-bulletinDone:
+		return bulletin, bulletinErr
+	case <-time.After(time.Until(bulletinDeadline)):
+		return dhs.Bulletin{}, fmt.Errorf("no bulletin within %v", BULLETIN_TIMEOUT)
+	}
+}
+
+// crossCheckHotspots marks the dashboard hotspots the bulletin also lists,
+// logs the LSGs found in only one of them and keeps the bulletin's list of
+// the day's changes.
+func crossCheckHotspots(hh *scraper.HotspotsHistory, dh dhs.HotspotsHistory) {
+	bulletin := dh.ToHistory()
+	hh.Added, hh.Removed = bulletin.Added, bulletin.Removed
+	if len(dh.Hotspots) == 0 {
+		log.Println("no hotspot list in the bulletin to cross-check")
+		return
+	}
+	onlyDashboard, onlyBulletin := scraper.CompareHotspots(hh.Hotspots, bulletin.Hotspots)
+	missing := make(map[string]bool)
+	for _, h := range onlyDashboard {
+		missing[h.District+"/"+h.LSGD] = true
+		log.Printf("hotspot only on the dashboard: %v, %v\n", h.District, h.LSGD)
+	}
+	for _, h := range onlyBulletin {
+		log.Printf("hotspot only in the bulletin: %v, %v\n", h.District, h.LSGD)
+	}
+	for i, h := range hh.Hotspots {
+		if !missing[h.District+"/"+h.LSGD] {
+			hh.Hotspots[i].Sources = append(h.Sources, dhs.BULLETIN_SOURCE)
+		}
+	}
+	log.Printf("cross-checked hotspots, %v only on the dashboard and %v only in the bulletin\n", len(onlyDashboard), len(onlyBulletin))
+}
+
+// writeHotspotsTimelines extends the timelines with the latest hotspot list
+// when they were built from exactly the lists before it, replaces that
+// list's day when they were built from exactly these lists, as on a rerun
+// the same day, and rebuilds them from the whole history otherwise, as when
+// a backfill inserted older lists. It returns the timelines written.
+func writeHotspotsTimelines(h []scraper.HotspotsHistory) scraper.Timelines {
+	var f HotspotsTimelines
+	if _, err := os.Stat(HOTSPOT_TIMELINES_FILE); err == nil {
+		ReadJSON(HOTSPOT_TIMELINES_FILE, &f)
+	}
+	t := scraper.Timelines{Timelines: f.Timelines, Through: f.Through, Previous: f.Previous}
+	n := len(h)
+	switch {
+	case f.builtFrom(h):
+		if err := t.Replace(h[n-1]); err != nil {
+			log.Panicln("ERROR updating hotspot timelines", err)
+		}
+	case n > 1 && f.builtFrom(h[:n-1]):
+		if err := t.Add(h[n-1]); err != nil {
+			log.Panicln("ERROR updating hotspot timelines", err)
+		}
+	default:
+		var err error
+		t, err = scraper.BuildTimelines(h)
+		if err != nil {
+			log.Panicln("ERROR building hotspot timelines", err)
+		}
+		log.Println("hotspots timelines rebuilt")
+	}
+	f.Timelines, f.Through, f.Previous, f.Fingerprint, f.LastUpdated = t.Timelines, t.Through, t.Previous, fingerprint(h), lastUpdated
+	WriteJSON(f, HOTSPOT_TIMELINES_FILE)
+	return t
+}
+
+// builtFrom reports whether the timelines were built from exactly the lists
+// of h.
+func (f HotspotsTimelines) builtFrom(h []scraper.HotspotsHistory) bool {
+	n := len(h)
+	if n == 0 || f.Through != h[n-1].Date || f.Fingerprint != fingerprint(h) {
+		return false
+	}
+	return n == 1 && f.Previous == "" || n > 1 && f.Previous == h[n-2].Date
+}
+
+// fingerprint hashes the dates of the hotspot lists.
+func fingerprint(h []scraper.HotspotsHistory) string {
+	hash := fnv.New64a()
+	for _, v := range h {
+		io.WriteString(hash, v.Date+"\n")
+	}
+	return fmt.Sprintf("%x", hash.Sum64())
 }
 
 // func handleZonesHistories() {
@@ -242,6 +474,16 @@ func handleHotspotsHistories() {
 // }
 
 func main() {
+	if err := LoadLSGAliases(LSG_ALIASES_FILE); err != nil {
+		log.Panicln("ERROR loading LSG aliases", err)
+	}
+	if err := LoadGazetteer(GAZETTEER_FILE); err != nil {
+		log.Panicln("ERROR loading gazetteer", err)
+	}
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
 	var err error
 	log.Println("started")
 	start := time.Now()
@@ -253,6 +495,7 @@ func main() {
 	}
 	log.Printf("last updated on %v", lastUpdated)
 	date = strings.Split(lastUpdated, " ")[0]
+	fetchBulletin()
 	wg.Add(1)
 	go handleHistories()
 	wg.Add(1)
@@ -262,5 +505,10 @@ func main() {
 	wg.Add(1)
 	go handleTestReports()
 	wg.Wait()
+	// the bulletin may still be downloading after its timeout
+	dhs.DefaultLibrary.Close()
+	if err := Reviews.Flush(REVIEW_QUEUE_FILE); err != nil {
+		log.Println("ERROR writing review queue", err)
+	}
 	log.Printf("completed in %v", time.Now().Sub(start))
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	. "scrape/common"
+)
+
+func reviewUsage() {
+	fmt.Fprintln(os.Stderr, `usage: scrape review <command> [arguments]
+
+  list            list the low-confidence matches awaiting review
+  accept id [lsg] map the name to lsg, exactly as named in the gazetteer,
+                  the best candidate by default
+  drop id         remove the item without adding an alias`)
+	os.Exit(2)
+}
+
+func reviewCommand(args []string) {
+	if len(args) < 1 {
+		reviewUsage()
+	}
+	items, err := ReadReviewQueue(REVIEW_QUEUE_FILE)
+	if err != nil {
+		log.Panicln("ERROR reading review queue", err)
+	}
+	if args[0] == "list" {
+		for _, v := range items {
+			fmt.Printf("%v\t%v\t%q\tseen %v times, last %v (%v)\n", v.ID, v.District, v.Raw, v.Count, v.LastSeen, v.Source)
+			for _, c := range v.Candidates {
+				fmt.Printf("\t\t%v\t%v\n", c.Score, c.Name)
+			}
+		}
+		return
+	}
+	if len(args) < 2 || (args[0] != "accept" && args[0] != "drop") {
+		reviewUsage()
+	}
+	i := -1
+	for j, v := range items {
+		if v.ID == args[1] {
+			i = j
+		}
+	}
+	if i < 0 {
+		log.Panicln("ERROR no review item", args[1])
+	}
+	item := items[i]
+	if args[0] == "accept" {
+		lsg := ""
+		if len(args) > 2 {
+			// the name is taken as typed, a typo must not become an alias
+			l, ok := LSGGazetteer.LookupAt(item.District, args[2], item.LastSeen)
+			if !ok {
+				log.Panicln("ERROR no LSG named", args[2], "in", item.District)
+			}
+			lsg = l.GeoName
+		} else if len(item.Candidates) > 0 {
+			lsg = item.Candidates[0].Name
+		}
+		if lsg == "" {
+			log.Panicln("ERROR no LSG to map", item.Raw, "to")
+		}
+		if err := SaveLSGAlias(LSG_ALIASES_FILE, item.District, item.Raw, lsg); err != nil {
+			log.Panicln("ERROR saving LSG alias", err)
+		}
+		log.Printf("%q in %v now resolves to %v\n", item.Raw, item.District, lsg)
+	}
+	items = append(items[:i], items[i+1:]...)
+	if err := WriteReviewQueue(items, REVIEW_QUEUE_FILE); err != nil {
+		log.Panicln("ERROR writing review queue", err)
+	}
+}
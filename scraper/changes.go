@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	. "scrape/common"
+)
+
+// HotspotsChange is an LSG listed on both days whose wards changed.
+type HotspotsChange struct {
+	District      string `json:"district"`
+	LSGD          string `json:"lsgd"`
+	Wards         string `json:"wards"`
+	PreviousWards string `json:"previous_wards"`
+	WardsAdded    []int  `json:"wards_added,omitempty"`
+	WardsRemoved  []int  `json:"wards_removed,omitempty"`
+	AllWards      bool   `json:"all_wards,omitempty"`
+	ExceptWards   []int  `json:"except_wards,omitempty"`
+}
+
+// HotspotsChanges is what changed in the hotspot list of Date relative to
+// the list of Since.
+type HotspotsChanges struct {
+	Added    []Hotspots       `json:"added"`
+	Removed  []Hotspots       `json:"removed"`
+	Modified []HotspotsChange `json:"modified"`
+	Date     string           `json:"date"`
+	Since    string           `json:"since"`
+}
+
+// wards returns the ward list parsed from Wards, so entries stored by
+// older versions of the parser are read the same way as new ones.
+func (h Hotspots) wards() ([]int, bool, []int) {
+	if h.Wards != "" {
+		return ParseWards(h.Wards)
+	}
+	return h.WardList, h.AllWards, h.ExceptWards
+}
+
+// difference returns the sorted wards in a but not in b.
+func difference(a []int, b []int) []int {
+	in := make(map[int]bool)
+	for _, w := range b {
+		in[w] = true
+	}
+	var out []int
+	for _, w := range a {
+		if !in[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// DiffHotspots returns the LSGs added to, removed from and with wards
+// changed in cur relative to prev.
+func DiffHotspots(prev HotspotsHistory, cur HotspotsHistory) HotspotsChanges {
+	c := HotspotsChanges{
+		Added:    make([]Hotspots, 0),
+		Removed:  make([]Hotspots, 0),
+		Modified: make([]HotspotsChange, 0),
+		Date:     cur.Date,
+		Since:    prev.Date,
+	}
+	c.Added, c.Removed = CompareHotspots(cur.Hotspots, prev.Hotspots)
+	if c.Added == nil {
+		c.Added = make([]Hotspots, 0)
+	}
+	if c.Removed == nil {
+		c.Removed = make([]Hotspots, 0)
+	}
+	for _, h := range cur.Hotspots {
+		p, ok := prev.Find(h.District, h.LSGD)
+		if !ok {
+			continue
+		}
+		cw, call, ce := h.wards()
+		pw, pall, pe := p.wards()
+		if call == pall && len(difference(cw, pw))+len(difference(pw, cw))+len(difference(ce, pe))+len(difference(pe, ce)) == 0 {
+			continue
+		}
+		c.Modified = append(c.Modified, HotspotsChange{
+			District:      h.District,
+			LSGD:          h.LSGD,
+			Wards:         h.Wards,
+			PreviousWards: p.Wards,
+			WardsAdded:    difference(cw, pw),
+			WardsRemoved:  difference(pw, cw),
+			AllWards:      call,
+			ExceptWards:   ce,
+		})
+	}
+	return c
+}
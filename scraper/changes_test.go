@@ -0,0 +1,46 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffHotspots(t *testing.T) {
+	list := func(date string, hotspots ...Hotspots) HotspotsHistory {
+		return HotspotsHistory{Hotspots: hotspots, Date: date}
+	}
+	munnar := func(wards string) Hotspots {
+		return Hotspots{District: "Idukki", LSGD: "Munnar", Wards: wards}
+	}
+	kollam := Hotspots{District: "Kollam", LSGD: "Kollam Corporation", Wards: "5, 6"}
+	tests := []struct {
+		name string
+		prev HotspotsHistory
+		cur  HotspotsHistory
+		want HotspotsChanges
+	}{
+		{"unchanged", list("01-05-2020", kollam, munnar("3")), list("02-05-2020", munnar("Ward 3"), kollam), HotspotsChanges{
+			Added: []Hotspots{}, Removed: []Hotspots{}, Modified: []HotspotsChange{}, Date: "02-05-2020", Since: "01-05-2020",
+		}},
+		{"added and removed", list("01-05-2020", kollam), list("02-05-2020", munnar("3")), HotspotsChanges{
+			Added: []Hotspots{munnar("3")}, Removed: []Hotspots{kollam}, Modified: []HotspotsChange{}, Date: "02-05-2020", Since: "01-05-2020",
+		}},
+		{"wards changed", list("01-05-2020", munnar("3, 4")), list("02-05-2020", munnar("4, 5")), HotspotsChanges{
+			Added: []Hotspots{}, Removed: []Hotspots{}, Date: "02-05-2020", Since: "01-05-2020",
+			Modified: []HotspotsChange{{District: "Idukki", LSGD: "Munnar", Wards: "4, 5", PreviousWards: "3, 4", WardsAdded: []int{5}, WardsRemoved: []int{3}}},
+		}},
+		{"whole LSG", list("01-05-2020", munnar("3")), list("02-05-2020", munnar("All wards")), HotspotsChanges{
+			Added: []Hotspots{}, Removed: []Hotspots{}, Date: "02-05-2020", Since: "01-05-2020",
+			Modified: []HotspotsChange{{District: "Idukki", LSGD: "Munnar", Wards: "All wards", PreviousWards: "3", WardsRemoved: []int{3}, AllWards: true}},
+		}},
+		{"exceptions changed", list("01-05-2020", munnar("All wards except 3")), list("02-05-2020", munnar("All wards except 3, 7")), HotspotsChanges{
+			Added: []Hotspots{}, Removed: []Hotspots{}, Date: "02-05-2020", Since: "01-05-2020",
+			Modified: []HotspotsChange{{District: "Idukki", LSGD: "Munnar", Wards: "All wards except 3, 7", PreviousWards: "All wards except 3", AllWards: true, ExceptWards: []int{3, 7}}},
+		}},
+	}
+	for _, tt := range tests {
+		if got := DiffHotspots(tt.prev, tt.cur); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
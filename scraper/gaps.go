@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	. "scrape/common"
+)
+
+// Placeholders returns a missing record for every date strictly between
+// last and today. Each carries the last summary and unassigned counts
+// forward with a zero delta, so the next reported day holds the whole
+// change since last.
+func Placeholders(last History, today string) ([]History, error) {
+	var out []History
+	from, err := ParseDate(last.Date)
+	if err != nil {
+		return out, err
+	}
+	to, err := ParseDate(today)
+	if err != nil {
+		return out, err
+	}
+	for t := from.AddDate(0, 0, 1); t.Before(to); t = t.AddDate(0, 0, 1) {
+		h := History{
+			Summary:    make(map[string]DistrictInfo),
+			Delta:      make(map[string]DistrictInfo),
+			Date:       FormatDate(t),
+			Missing:    true,
+			Unassigned: last.Unassigned,
+		}
+		for d, info := range last.Summary {
+			h.Summary[d] = info
+			h.Delta[d] = DistrictInfo{}
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// DeltaSpan returns the number of days between the entry that would follow
+// h and the latest entry of h that is not a placeholder.
+func DeltaSpan(h []History, today string) int {
+	to, err := ParseDate(today)
+	if err != nil {
+		return 1
+	}
+	for i := len(h) - 1; i >= 0; i-- {
+		if h[i].Missing {
+			continue
+		}
+		from, err := ParseDate(h[i].Date)
+		if err != nil {
+			return 1
+		}
+		return DaysBetween(from, to)
+	}
+	return 1
+}
+
+// FillGaps inserts placeholders for every missing day in h, which must
+// already be sorted by date, and recomputes each delta and its span.
+func FillGaps(h []History) []History {
+	var out []History
+	for i, cur := range h {
+		if i > 0 {
+			p, err := Placeholders(h[i-1], cur.Date)
+			if err == nil {
+				out = append(out, p...)
+			}
+			prev := out[len(out)-1]
+			if cur.Missing {
+				cur.Summary = make(map[string]DistrictInfo)
+				for d, info := range prev.Summary {
+					cur.Summary[d] = info
+				}
+				cur.Unassigned = prev.Unassigned
+			}
+			cur.Delta = ComputeDelta(cur.Summary, prev.Summary)
+			cur.UnassignedDelta = ComputeUnassignedDelta(cur.Unassigned, prev.Unassigned)
+			cur.DeltaDays = 0
+			if !cur.Missing {
+				if span := DeltaSpan(out, cur.Date); span > 1 {
+					cur.DeltaDays = span
+				}
+			}
+		}
+		out = append(out, cur)
+	}
+	return out
+}
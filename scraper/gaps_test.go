@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+// summaryOn returns the history of a date with Kollam at confirmed cases.
+func summaryOn(date string, confirmed int) History {
+	return History{Summary: map[string]DistrictInfo{"Kollam": {Confirmed: confirmed, Active: confirmed}}, Date: date}
+}
+
+func TestFillGaps(t *testing.T) {
+	stale := summaryOn("02-05-2020", 3)
+	stale.Missing = true
+	tests := []struct {
+		name string
+		in   []History
+		// want holds the date, confirmed count, delta, delta_days and
+		// missing flag of every entry out
+		want [][5]interface{}
+	}{
+		{"consecutive", []History{summaryOn("01-05-2020", 10), summaryOn("02-05-2020", 12)}, [][5]interface{}{
+			{"01-05-2020", 10, 0, 0, false},
+			{"02-05-2020", 12, 2, 0, false},
+		}},
+		{"gap", []History{summaryOn("01-05-2020", 10), summaryOn("04-05-2020", 16)}, [][5]interface{}{
+			{"01-05-2020", 10, 0, 0, false},
+			{"02-05-2020", 10, 0, 0, true},
+			{"03-05-2020", 10, 0, 0, true},
+			{"04-05-2020", 16, 6, 3, false},
+		}},
+		{"stale placeholder", []History{summaryOn("01-05-2020", 10), stale, summaryOn("03-05-2020", 15)}, [][5]interface{}{
+			{"01-05-2020", 10, 0, 0, false},
+			{"02-05-2020", 10, 0, 0, true},
+			{"03-05-2020", 15, 5, 2, false},
+		}},
+	}
+	for _, tt := range tests {
+		out := FillGaps(tt.in)
+		var got [][5]interface{}
+		for _, h := range out {
+			got = append(got, [5]interface{}{h.Date, h.Summary["Kollam"].Confirmed, h.Delta["Kollam"].Confirmed, h.DeltaDays, h.Missing})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	last := summaryOn("30-04-2020", 7)
+	p, err := Placeholders(last, "03-05-2020")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dates []string
+	for _, h := range p {
+		dates = append(dates, h.Date)
+		if !h.Missing || h.Summary["Kollam"] != last.Summary["Kollam"] || h.Delta["Kollam"] != (DistrictInfo{}) {
+			t.Errorf("%v: got %+v, want a placeholder carrying %+v", h.Date, h, last.Summary)
+		}
+	}
+	if want := []string{"01-05-2020", "02-05-2020"}; !reflect.DeepEqual(dates, want) {
+		t.Errorf("got placeholders for %v, want %v", dates, want)
+	}
+	if p, _ := Placeholders(last, "01-05-2020"); len(p) != 0 {
+		t.Errorf("got %v placeholders between consecutive days", len(p))
+	}
+}
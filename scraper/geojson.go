@@ -2,63 +2,181 @@ package scraper
 
 import (
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"regexp"
+	"strconv"
+	"strings"
+
 	. "scrape/common"
 )
 
-func scrapeGeoJSON() (map[string][]string, error) {
-	var body io.ReadCloser
-	data := make(map[string][]string)
+// GEOJSON_SOURCE marks case counts read from the dashboard's map.
+const GEOJSON_SOURCE = "dashboard-geojson"
+
+// GeoDistrict is a district of the dashboard's map with its geometry as
+// published and the case counts shown on it.
+type GeoDistrict struct {
+	District string
+	Geometry json.RawMessage
+	Counts   DistrictInfo
+}
+
+// geoCount reads a count published either as a number or as a string.
+type geoCount int
+
+func (c *geoCount) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*c = 0
+		return nil
+	}
+	n, err := strconv.Atoi(strings.ReplaceAll(s, ",", ""))
+	if err != nil {
+		return fmt.Errorf("invalid count %s", b)
+	}
+	*c = geoCount(n)
+	return nil
+}
+
+var reGeoJSONPath = regexp.MustCompile(`maps/[^"'\s]*outside\.geojson`)
+
+// ScrapeGeoJSON downloads the district map of the dashboard's home page.
+func ScrapeGeoJSON() ([]GeoDistrict, error) {
 	baseurl := "https://dashboard.kerala.gov.in/"
 	body, err := makeRequest(baseurl+"index.php", baseurl+"index.php")
-	defer body.Close()
 	if err != nil {
-		return data, err
+		return nil, err
 	}
 	s, err := ioutil.ReadAll(body)
+	body.Close()
 	if err != nil {
-		return data, err
+		return nil, err
+	}
+	li := reGeoJSONPath.FindString(string(s))
+	if li == "" {
+		return nil, errors.New("error finding the geojson link")
 	}
-	li := regexp.MustCompile(`maps/.*outside.geojson`).FindString(string(s))
 	body, err = makeRequest(baseurl+li, baseurl+"index.php")
-	defer body.Close()
+	if err != nil {
+		return nil, err
+	}
 	s, err = ioutil.ReadAll(body)
+	body.Close()
 	if err != nil {
-		return data, err
+		return nil, err
 	}
+	return parseGeoJSON(s)
+}
+
+// parseGeoJSON reads the districts of the map, which must cover every
+// district exactly once.
+func parseGeoJSON(s []byte) ([]GeoDistrict, error) {
 	var geoJSON struct {
-		Crs struct {
-			Properties struct {
-				Name string `json:"name"`
-			} `json:"properties"`
-			Type string `json:"type"`
-		} `json:"crs"`
 		Features []struct {
-			Geometry struct {
-				Coordinates [][][]float64 `json:"coordinates"`
-				Type        string        `json:"type"`
-			} `json:"geometry"`
+			Geometry   json.RawMessage `json:"geometry"`
 			Properties struct {
-				District        string `json:"District"`
-				Objectid        int64  `json:"OBJECTID"`
-				CovidStat       int64  `json:"covid_stat"`
-				CovidStatactive int64  `json:"covid_statactive"`
-				CovidStatcured  int64  `json:"covid_statcured"`
-				CovidStatdeath  int64  `json:"covid_statdeath"`
+				District        string   `json:"District"`
+				CovidStat       geoCount `json:"covid_stat"`
+				CovidStatactive geoCount `json:"covid_statactive"`
+				CovidStatcured  geoCount `json:"covid_statcured"`
+				CovidStatdeath  geoCount `json:"covid_statdeath"`
 			} `json:"properties"`
-			Type string `json:"type"`
 		} `json:"features"`
-		Type string `json:"type"`
 	}
-	err = json.Unmarshal(s, &geoJSON)
-	if err != nil {
-		return data, err
+	if err := json.Unmarshal(s, &geoJSON); err != nil {
+		return nil, err
 	}
+	var districts []GeoDistrict
+	seen := make(map[string]bool)
 	for _, v := range geoJSON.Features {
 		p := v.Properties
-		data[FuzzySearch(p.District, DistrictList).Match] = []string{Itoa(p.CovidStat), Itoa(p.CovidStatcured), Itoa(p.CovidStatdeath), Itoa(p.CovidStatactive)}
+		d := MatchDistrict(p.District)
+		if d.Score < 80 {
+			log.Printf("unknown district in geojson: %q\n", p.District)
+			continue
+		}
+		if seen[d.Match] {
+			return nil, fmt.Errorf("district %v is in the geojson twice", d.Match)
+		}
+		seen[d.Match] = true
+		districts = append(districts, GeoDistrict{
+			District: d.Match,
+			Geometry: v.Geometry,
+			Counts: DistrictInfo{
+				Confirmed: int(p.CovidStat),
+				Recovered: int(p.CovidStatcured),
+				Deceased:  int(p.CovidStatdeath),
+				Active:    int(p.CovidStatactive),
+			},
+		})
+	}
+	for _, d := range DistrictList {
+		if !seen[d] {
+			return nil, fmt.Errorf("district %v is missing from the geojson", d)
+		}
+	}
+	return districts, nil
+}
+
+// Conflict is a count on which the case table and the geojson disagree,
+// with the source whose value was kept.
+type Conflict struct {
+	District string
+	Field    string
+	Table    int
+	GeoJSON  int
+	Chosen   string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%v %v: table %v, geojson %v, kept %v", c.District, c.Field, c.Table, c.GeoJSON, c.Chosen)
+}
+
+// consistentCases reports whether the case counts add up and none of the
+// cumulative ones fell since prev.
+func consistentCases(i DistrictInfo, prev DistrictInfo) bool {
+	return i.Active == i.Confirmed-i.Recovered-i.Deceased &&
+		i.Confirmed >= prev.Confirmed && i.Recovered >= prev.Recovered && i.Deceased >= prev.Deceased
+}
+
+// CrossValidate compares the case counts of summary, read from the case
+// table, with those of the geojson. Where they disagree the geojson counts
+// of the district are taken if only they are consistent with themselves and
+// with prev, the table's otherwise. It returns every disagreement.
+func CrossValidate(summary map[string]DistrictInfo, geo []GeoDistrict, prev map[string]DistrictInfo) []Conflict {
+	var conflicts []Conflict
+	for _, g := range geo {
+		t, ok := summary[g.District]
+		if !ok {
+			continue
+		}
+		fields := []struct {
+			name       string
+			table, geo int
+		}{
+			{"confirmed", t.Confirmed, g.Counts.Confirmed},
+			{"recovered", t.Recovered, g.Counts.Recovered},
+			{"deceased", t.Deceased, g.Counts.Deceased},
+			{"active", t.Active, g.Counts.Active},
+		}
+		chosen := "table"
+		if !consistentCases(t, prev[g.District]) && consistentCases(g.Counts, prev[g.District]) {
+			chosen = "geojson"
+		}
+		differs := false
+		for _, f := range fields {
+			if f.table != f.geo {
+				differs = true
+				conflicts = append(conflicts, Conflict{District: g.District, Field: f.name, Table: f.table, GeoJSON: f.geo, Chosen: chosen})
+			}
+		}
+		if differs && chosen == "geojson" {
+			t.Confirmed, t.Recovered, t.Deceased, t.Active = g.Counts.Confirmed, g.Counts.Recovered, g.Counts.Deceased, g.Counts.Active
+			summary[g.District] = t
+		}
 	}
-	return data, nil
+	return conflicts
 }
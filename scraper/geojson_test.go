@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "scrape/common"
+)
+
+// mapOf returns a FeatureCollection of the features, with a point feature
+// counting 1 confirmed and active case for every district not among skip.
+func mapOf(features []string, skip ...string) []byte {
+	for _, d := range DistrictList {
+		listed := false
+		for _, s := range skip {
+			listed = listed || s == d
+		}
+		for _, f := range features {
+			listed = listed || strings.Contains(f, `"`+d+`"`)
+		}
+		if !listed {
+			features = append(features, fmt.Sprintf(`{"type":"Feature","geometry":{"type":"Point","coordinates":[76,10]},"properties":{"District":%q,"covid_stat":1,"covid_statactive":1}}`, d))
+		}
+	}
+	return []byte(`{"type":"FeatureCollection","features":[` + strings.Join(features, ",") + `]}`)
+}
+
+const (
+	kollam    = `{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[76.5,8.9],[76.7,8.9],[76.7,9.1],[76.5,8.9]]]},"properties":{"District":"Kollam","covid_stat":1204,"covid_statcured":980,"covid_statdeath":4,"covid_statactive":220}}`
+	idukki    = `{"type":"Feature","geometry":null,"properties":{"District":"Idukki","covid_stat":"2,345","covid_statcured":"1,100","covid_statdeath":"7","covid_statactive":"1238"}}`
+	kasaragod = `{"type":"Feature","geometry":null,"properties":{"District":"Kasaragod","covid_stat":"12","covid_statcured":"","covid_statdeath":null,"covid_statactive":12}}`
+)
+
+func TestParseGeoJSON(t *testing.T) {
+	districts, err := parseGeoJSON(mapOf([]string{kollam, idukki, kasaragod}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(districts) != len(DistrictList) {
+		t.Fatalf("got %v districts, want %v", len(districts), len(DistrictList))
+	}
+	got := make(map[string]GeoDistrict)
+	for _, d := range districts {
+		got[d.District] = d
+	}
+	tests := []struct {
+		district string
+		want     DistrictInfo
+	}{
+		{"Kollam", DistrictInfo{Confirmed: 1204, Recovered: 980, Deceased: 4, Active: 220}},
+		{"Idukki", DistrictInfo{Confirmed: 2345, Recovered: 1100, Deceased: 7, Active: 1238}},
+		{"Kasaragod", DistrictInfo{Confirmed: 12, Active: 12}},
+		{"Wayanad", DistrictInfo{Confirmed: 1, Active: 1}},
+	}
+	for _, tt := range tests {
+		if c := got[tt.district].Counts; c != tt.want {
+			t.Errorf("%v counts %+v, want %+v", tt.district, c, tt.want)
+		}
+	}
+	if g := string(got["Kollam"].Geometry); !strings.HasPrefix(g, `{"type":"Polygon"`) {
+		t.Errorf("Kollam geometry %s, want the polygon", g)
+	}
+}
+
+func TestParseGeoJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		err  string
+	}{
+		{"invalid count", mapOf([]string{`{"properties":{"District":"Kollam","covid_stat":"twelve"}}`}), `invalid count "twelve"`},
+		{"missing district", mapOf(nil, "Wayanad"), "Wayanad is missing"},
+		{"duplicate district", mapOf([]string{idukki, idukki}), "Idukki is in the geojson twice"},
+		{"district alias", mapOf([]string{strings.Replace(kollam, `"Kollam"`, `"Quilon"`, 1), kollam}), "Kollam is in the geojson twice"},
+		{"not json", []byte(`<html>`), "invalid character"},
+	}
+	for _, tt := range tests {
+		_, err := parseGeoJSON(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%v: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestCrossValidate(t *testing.T) {
+	prev := DistrictInfo{Confirmed: 100, Recovered: 60, Deceased: 1, Active: 39}
+	good := DistrictInfo{Confirmed: 110, Recovered: 62, Deceased: 1, Active: 47}
+	other := DistrictInfo{Confirmed: 112, Recovered: 62, Deceased: 1, Active: 49}
+	// active doesn't add up
+	unbalanced := DistrictInfo{Confirmed: 111, Recovered: 62, Deceased: 1, Active: 40}
+	// recovered fell since the previous day
+	fallen := DistrictInfo{Confirmed: 110, Recovered: 50, Deceased: 1, Active: 59}
+	tests := []struct {
+		name       string
+		table, geo DistrictInfo
+		want       DistrictInfo
+		conflicts  []Conflict
+	}{
+		{"agree", good, good, good, nil},
+		{"both consistent", good, other, good, []Conflict{
+			{"Kollam", "confirmed", 110, 112, "table"},
+			{"Kollam", "active", 47, 49, "table"},
+		}},
+		{"table unbalanced", unbalanced, good, good, []Conflict{
+			{"Kollam", "confirmed", 111, 110, "geojson"},
+			{"Kollam", "active", 40, 47, "geojson"},
+		}},
+		{"table fell", fallen, good, good, []Conflict{
+			{"Kollam", "recovered", 50, 62, "geojson"},
+			{"Kollam", "active", 59, 47, "geojson"},
+		}},
+		{"geojson unbalanced", good, unbalanced, good, []Conflict{
+			{"Kollam", "confirmed", 110, 111, "table"},
+			{"Kollam", "active", 47, 40, "table"},
+		}},
+		{"both inconsistent", unbalanced, fallen, unbalanced, []Conflict{
+			{"Kollam", "confirmed", 111, 110, "table"},
+			{"Kollam", "recovered", 62, 50, "table"},
+			{"Kollam", "active", 40, 59, "table"},
+		}},
+	}
+	for _, tt := range tests {
+		summary := map[string]DistrictInfo{"Kollam": tt.table, "Idukki": good}
+		geo := []GeoDistrict{{District: "Kollam", Counts: tt.geo}, {District: "Idukki", Counts: good}, {District: "Wayanad", Counts: good}}
+		conflicts := CrossValidate(summary, geo, map[string]DistrictInfo{"Kollam": prev, "Idukki": prev})
+		if summary["Kollam"] != tt.want {
+			t.Errorf("%v: kept %+v, want %+v", tt.name, summary["Kollam"], tt.want)
+		}
+		if summary["Idukki"] != good {
+			t.Errorf("%v: Idukki changed to %+v", tt.name, summary["Idukki"])
+		}
+		if _, ok := summary["Wayanad"]; ok {
+			t.Errorf("%v: added Wayanad, which the table doesn't have", tt.name)
+		}
+		if fmt.Sprint(conflicts) != fmt.Sprint(tt.conflicts) {
+			t.Errorf("%v: got conflicts %v, want %v", tt.name, conflicts, tt.conflicts)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package scraper
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,6 +31,80 @@ type History struct {
 	Summary map[string]DistrictInfo `json:"summary"`
 	Delta   map[string]DistrictInfo `json:"delta"`
 	Date    string                  `json:"date"`
+	// Missing marks a placeholder for a day that could not be scraped.
+	Missing bool `json:"missing,omitempty"`
+	// DeltaDays is the number of days covered by Delta when more than one.
+	DeltaDays int `json:"delta_days,omitempty"`
+	// Source records where the entry came from when not the dashboard.
+	Source string `json:"source,omitempty"`
+	// Unassigned is the official state total less the sum of the
+	// districts when they differ, such as patients from other states, and
+	// UnassignedDelta its change since the previous entry.
+	Unassigned      *DistrictInfo `json:"unassigned,omitempty"`
+	UnassignedDelta *DistrictInfo `json:"unassigned_delta,omitempty"`
+}
+
+// Sub returns the field-wise difference d - o.
+func (d DistrictInfo) Sub(o DistrictInfo) DistrictInfo {
+	return DistrictInfo{
+		HospitalObservation: d.HospitalObservation - o.HospitalObservation,
+		HomeObservation:     d.HomeObservation - o.HomeObservation,
+		TotalObservation:    d.TotalObservation - o.TotalObservation,
+		HospitalizedToday:   d.HospitalizedToday - o.HospitalizedToday,
+		Confirmed:           d.Confirmed - o.Confirmed,
+		Recovered:           d.Recovered - o.Recovered,
+		Deceased:            d.Deceased - o.Deceased,
+		Active:              d.Active - o.Active,
+	}
+}
+
+// Add returns the field-wise sum d + o.
+func (d DistrictInfo) Add(o DistrictInfo) DistrictInfo {
+	return DistrictInfo{
+		HospitalObservation: d.HospitalObservation + o.HospitalObservation,
+		HomeObservation:     d.HomeObservation + o.HomeObservation,
+		TotalObservation:    d.TotalObservation + o.TotalObservation,
+		HospitalizedToday:   d.HospitalizedToday + o.HospitalizedToday,
+		Confirmed:           d.Confirmed + o.Confirmed,
+		Recovered:           d.Recovered + o.Recovered,
+		Deceased:            d.Deceased + o.Deceased,
+		Active:              d.Active + o.Active,
+	}
+}
+
+// SumDistricts returns the field-wise sum of the districts.
+func SumDistricts(m map[string]DistrictInfo) DistrictInfo {
+	var sum DistrictInfo
+	for _, info := range m {
+		sum = sum.Add(info)
+	}
+	return sum
+}
+
+// ComputeUnassignedDelta returns the change of the unassigned counts from
+// prev to cur, nil when there is none.
+func ComputeUnassignedDelta(cur, prev *DistrictInfo) *DistrictInfo {
+	var c, p DistrictInfo
+	if cur != nil {
+		c = *cur
+	}
+	if prev != nil {
+		p = *prev
+	}
+	if c == p {
+		return nil
+	}
+	d := c.Sub(p)
+	return &d
+}
+
+// ComputeDelta returns the per-district change from prev to cur.
+func ComputeDelta(cur, prev map[string]DistrictInfo) map[string]DistrictInfo {
+	delta := make(map[string]DistrictInfo)
+	for d, info := range cur {
+		delta[d] = info.Sub(prev[d])
+	}
+	return delta
 }
 
 type TestReport struct {
@@ -36,6 +113,7 @@ type TestReport struct {
 	Today         int    `json:"today"`
 	Positive      int    `json:"positive"`
 	TodayPositive int    `json:"today_positive"`
+	Source        string `json:"source,omitempty"`
 }
 
 func ScrapeLastUpdated() (string, error) {
@@ -91,76 +169,216 @@ func ScrapeTodaysTestReport(today string) (TestReport, error) {
 	return b, nil
 }
 
-func scrapeTable(doc goquery.Document, selector string) map[string][]string {
+var reTotalRow = regexp.MustCompile(`(?i)^\s*(total|kerala|state)\b`)
+
+// districtTable holds the rows of a district-wise table by district, its
+// state total row and the rows naming no known district.
+type districtTable struct {
+	rows    map[string][]string
+	total   []string
+	unknown [][]string
+}
+
+// missing returns the districts without a row of at least n values.
+func (t districtTable) missing(n int) []string {
+	var missing []string
+	for _, d := range DistrictList {
+		if len(t.rows[d]) < n {
+			missing = append(missing, d)
+		}
+	}
+	return missing
+}
+
+// counts reads the first cells of every district's row as the named
+// columns, failing on a cell that isn't a count.
+func (t districtTable) counts(columns ...string) (map[string][]int, error) {
+	counts := make(map[string][]int)
+	for _, d := range DistrictList {
+		row := t.rows[d]
+		v := make([]int, len(columns))
+		for i, c := range columns {
+			if i >= len(row) {
+				return nil, fmt.Errorf("no %v count for %v", c, d)
+			}
+			n, ok := parseCount(row[i])
+			if !ok {
+				return nil, fmt.Errorf("unreadable %v count %q for %v", c, row[i], d)
+			}
+			v[i] = n
+		}
+		counts[d] = v
+	}
+	return counts, nil
+}
+
+// parseCount reads a count from a table cell, ignoring thousands
+// separators.
+func parseCount(s string) (int, bool) {
+	n, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(s), ",", ""))
+	return n, err == nil
+}
+
+// stateTotal reads the state total rows of the case and observation tables,
+// or reports false when either is missing or unreadable.
+func stateTotal(cases, observations districtTable) (DistrictInfo, bool) {
+	var v [8]int
+	cells := append(append([]string{}, firstN(cases.total, 4)...), firstN(observations.total, 4)...)
+	if len(cells) != 8 {
+		return DistrictInfo{}, false
+	}
+	for i, c := range cells {
+		n, ok := parseCount(c)
+		if !ok {
+			return DistrictInfo{}, false
+		}
+		v[i] = n
+	}
+	return DistrictInfo{
+		Confirmed:           v[0],
+		Recovered:           v[1],
+		Active:              v[2],
+		Deceased:            v[3],
+		TotalObservation:    v[4],
+		HospitalObservation: v[5],
+		HomeObservation:     v[6],
+		HospitalizedToday:   v[7],
+	}, true
+}
+
+func firstN(s []string, n int) []string {
+	if len(s) < n {
+		return s
+	}
+	return s[:n]
+}
+
+func scrapeTable(doc goquery.Document, selector string) districtTable {
 	var row []string
-	data := make(map[string][]string)
+	t := districtTable{rows: make(map[string][]string)}
 	doc.Find(selector).Each(func(index int, tablehtml *goquery.Selection) {
 		tablehtml.Find("tr").Each(func(indextr int, rowhtml *goquery.Selection) {
 			rowhtml.Find("td").Each(func(indexth int, tablecell *goquery.Selection) {
-				row = append(row, tablecell.Text())
+				row = append(row, strings.TrimSpace(tablecell.Text()))
 			})
-			data[DistrictMap[row[0]]] = row[1:]
+			cells := row
 			row = nil
+			if len(cells) < 2 {
+				return
+			}
+			if reTotalRow.MatchString(cells[0]) {
+				t.total = cells[1:]
+				return
+			}
+			d := MatchDistrict(cells[0])
+			if d.Score < 80 {
+				t.unknown = append(t.unknown, cells)
+				return
+			}
+			t.rows[d.Match] = cells[1:]
 		})
 	})
-	return data
+	return t
+}
+
+// scrapeDistrictTable scrapes a district-wise table of at least four
+// columns, failing when any district is missing.
+func scrapeDistrictTable(url string, selector string, name string) (districtTable, error) {
+	var t districtTable
+	doc, err := getDoc(url, url)
+	if err != nil {
+		return t, err
+	}
+	t = scrapeTable(*doc, selector)
+	if len(t.rows) < 1 {
+		return t, fmt.Errorf("error scraping %v", name)
+	}
+	for _, row := range t.unknown {
+		log.Printf("unknown row in %v: %q\n", name, row)
+	}
+	if missing := t.missing(4); len(missing) > 0 {
+		return t, fmt.Errorf("%v has no complete row for %v (%v unknown rows)", name, strings.Join(missing, ", "), len(t.unknown))
+	}
+	return t, nil
 }
 
-func ScrapeTodaysHistory(today string, last History) (History, error) {
+// ScrapeTodaysHistory scrapes the district-wise case and observation
+// tables. The case counts are cross-validated with geo, the dashboard's map
+// when it could be scraped, which stands in for the case table when that
+// can't be.
+func ScrapeTodaysHistory(today string, last History, geo []GeoDistrict) (History, error) {
 	var b History
 	start := time.Now()
 	url1 := "https://dashboard.kerala.gov.in/dailyreporting-view-public-districtwise.php"
 	url2 := "https://dashboard.kerala.gov.in/quarantined-datewise.php"
 
-	// data1, err := scrapeGeoJSON()
-	// if err != nil {
-	// 	return b, err
-	// }
-	doc, err := getDoc(url1, url1)
-	if err != nil {
+	data1, err := scrapeDistrictTable(url1, "section.col-lg-6:nth-child(5) > div:nth-child(1) > div:nth-child(2) > div:nth-child(1) > table:nth-child(1) > tbody:nth-child(3)", "table1")
+	var cases map[string][]int
+	if err == nil {
+		cases, err = data1.counts("confirmed", "recovered", "active", "deceased")
+	}
+	if err != nil && geo == nil {
 		return b, err
 	}
-	data1 := scrapeTable(*doc, "section.col-lg-6:nth-child(5) > div:nth-child(1) > div:nth-child(2) > div:nth-child(1) > table:nth-child(1) > tbody:nth-child(3)")
-	if len(data1) < 1 {
-		return b, errors.New("error scraping table1")
+	casesFromGeo := err != nil
+	if casesFromGeo {
+		log.Println("using the geojson case counts:", err)
 	}
-	doc, err = getDoc(url2, url2)
+	data2, err := scrapeDistrictTable(url2, "table.table:nth-child(1) > tbody:nth-child(3)", "table2")
 	if err != nil {
 		return b, err
 	}
-	data2 := scrapeTable(*doc, "table.table:nth-child(1) > tbody:nth-child(3)")
-	if len(data2) < 1 {
-		return b, errors.New("error scraping table2")
+	observations, err := data2.counts("total observation", "hospital observation", "home observation", "hospitalized today")
+	if err != nil {
+		return b, err
 	}
 	b = History{Summary: make(map[string]DistrictInfo), Delta: make(map[string]DistrictInfo), Date: today}
-	// fix for tamilnadu resident
-	if today == "06-06-2020" {
-		data1["Palakkad"][3] = Itoa(int64(Atoi(data1["Palakkad"][3]) - 1))
-	}
-	for _, d := range DistrictMap {
-		b.Summary[d] = DistrictInfo{
-			Confirmed:           Atoi(data1[d][0]),
-			Recovered:           Atoi(data1[d][1]),
-			Active:              Atoi(data1[d][2]),
-			Deceased:            Atoi(data1[d][3]),
-			TotalObservation:    Atoi(data2[d][0]),
-			HospitalObservation: Atoi(data2[d][1]),
-			HomeObservation:     Atoi(data2[d][2]),
-			HospitalizedToday:   Atoi(data2[d][3]),
+	if casesFromGeo {
+		b.Source = GEOJSON_SOURCE
+		for _, g := range geo {
+			b.Summary[g.District] = g.Counts
 		}
-		b.Delta[d] = DistrictInfo{
-			Confirmed:           Atoi(data1[d][0]) - last.Summary[d].Confirmed,
-			Recovered:           Atoi(data1[d][1]) - last.Summary[d].Recovered,
-			Active:              Atoi(data1[d][2]) - last.Summary[d].Active,
-			Deceased:            Atoi(data1[d][3]) - last.Summary[d].Deceased,
-			TotalObservation:    Atoi(data2[d][0]) - last.Summary[d].TotalObservation,
-			HospitalObservation: Atoi(data2[d][1]) - last.Summary[d].HospitalObservation,
-			HomeObservation:     Atoi(data2[d][2]) - last.Summary[d].HomeObservation,
-			HospitalizedToday:   Atoi(data2[d][3]) - last.Summary[d].HospitalizedToday,
+	} else {
+		// fix for tamilnadu resident
+		if today == "06-06-2020" {
+			cases["Palakkad"][3]--
 		}
+		for _, d := range DistrictList {
+			r1 := cases[d]
+			b.Summary[d] = DistrictInfo{
+				Confirmed: r1[0],
+				Recovered: r1[1],
+				Active:    r1[2],
+				Deceased:  r1[3],
+			}
+		}
+		if geo != nil {
+			for _, c := range CrossValidate(b.Summary, geo, last.Summary) {
+				log.Println("case count conflict:", c)
+			}
+		}
+	}
+	for _, d := range DistrictList {
+		r2 := observations[d]
+		info := b.Summary[d]
+		info.TotalObservation = r2[0]
+		info.HospitalObservation = r2[1]
+		info.HomeObservation = r2[2]
+		info.HospitalizedToday = r2[3]
+		b.Summary[d] = info
 	}
+	if total, ok := stateTotal(data1, data2); ok {
+		if diff := total.Sub(SumDistricts(b.Summary)); diff != (DistrictInfo{}) {
+			log.Printf("state total differs from the sum of the districts by %+v\n", diff)
+			b.Unassigned = &diff
+		}
+	} else {
+		log.Println("no state total to cross-check the districts with")
+	}
+	b.Delta = ComputeDelta(b.Summary, last.Summary)
+	b.UnassignedDelta = ComputeUnassignedDelta(b.Unassigned, last.Unassigned)
 	log.Printf("scraped latest history (%v) in %v\n", today, time.Now().Sub(start))
-	return b, err
+	return b, nil
 }
 
 func LatestSummary(h History) (DistrictInfo, DistrictInfo) {
@@ -205,18 +423,90 @@ func LatestSummary(h History) (DistrictInfo, DistrictInfo) {
 		TotalObservation:    dtot,
 		HospitalizedToday:   dtod,
 	}
+	if h.Unassigned != nil {
+		summary = summary.Add(*h.Unassigned)
+	}
+	if h.UnassignedDelta != nil {
+		delta = delta.Add(*h.UnassignedDelta)
+	}
 	return summary, delta
 }
 
+// DASHBOARD_SOURCE marks data scraped from the dashboard.
+const DASHBOARD_SOURCE = "dashboard"
+
 type Hotspots struct {
 	District string `json:"district"`
 	LSGD     string `json:"lsgd"`
-	Wards    string `json:"wards"`
+	// LSGID is the stable ID of the LSG in the gazetteer.
+	LSGID string `json:"lsg_id,omitempty"`
+	// Wards is the ward list as published, WardList, AllWards and
+	// ExceptWards are read from it.
+	Wards       string `json:"wards"`
+	WardList    []int  `json:"ward_list,omitempty"`
+	AllWards    bool   `json:"all_wards,omitempty"`
+	ExceptWards []int  `json:"except_wards,omitempty"`
+	// Sources lists every source the hotspot was found in.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// NewHotspots returns the hotspot listed on the date with its ward list
+// parsed and the ID of the LSG valid on that date.
+func NewHotspots(district string, lsg string, wards string, source string, date string) Hotspots {
+	h := Hotspots{District: district, LSGD: lsg, LSGID: LSGGazetteer.LSGID(district, lsg, date), Wards: wards, Sources: []string{source}}
+	h.WardList, h.AllWards, h.ExceptWards = ParseWards(wards)
+	return h
+}
+
+// WholeLSG reports whether the hotspot covers the whole LSG, either listing
+// all wards without exceptions or no wards at all.
+func (h Hotspots) WholeLSG() bool {
+	wards, all, except := h.wards()
+	if all {
+		return len(except) == 0
+	}
+	return len(wards) == 0
+}
+
+// HasWard reports whether the ward lies in the hotspot. A hotspot without
+// any ward numbers covers the whole LSG.
+func (h Hotspots) HasWard(ward int) bool {
+	wards, all, except := h.wards()
+	if all {
+		return !hasInt(except, ward)
+	}
+	return len(wards) == 0 || hasInt(wards, ward)
+}
+
+// hasInt reports whether n is in the sorted list.
+func hasInt(list []int, n int) bool {
+	i := sort.SearchInts(list, n)
+	return i < len(list) && list[i] == n
+}
+
+// Find returns the hotspot of the LSG in the district.
+func (h HotspotsHistory) Find(district string, lsg string) (Hotspots, bool) {
+	for _, v := range h.Hotspots {
+		if v.District == district && v.LSGD == lsg {
+			return v, true
+		}
+	}
+	return Hotspots{}, false
+}
+
+// IsHotspot reports whether the ward of the LSG in the district is listed.
+func (h HotspotsHistory) IsHotspot(district string, lsg string, ward int) bool {
+	v, ok := h.Find(district, lsg)
+	return ok && v.HasWard(ward)
 }
 
 type HotspotsHistory struct {
 	Hotspots []Hotspots `json:"hotspots"`
-	Date     string     `json:"date"`
+	// Added and Removed are the day's changes as listed by the DHS bulletin.
+	Added   []Hotspots `json:"added,omitempty"`
+	Removed []Hotspots `json:"removed,omitempty"`
+	Date    string     `json:"date"`
+	Source  string     `json:"source,omitempty"`
 }
 
 func ScrapeHotspotsHistory(today string) (HotspotsHistory, error) {
@@ -237,30 +527,15 @@ func ScrapeHotspotsHistory(today string) (HotspotsHistory, error) {
 				row = append(row, tablecell.Text())
 			})
 			if len(row) != 0 {
-				if row[2] == "Koothuparamba (M)" {
-					row[2] = "Kuthuparambu (M)"
-				}
-				if row[2] == "Mattanur (M)" {
-					row[2] = "Mattannoor (M)"
-				}
-				if row[2] == "Maloor" {
-					row[2] = "Malur"
-				}
-				if row[2] == "Changanacherry (M)" {
-					row[2] = "Changanassery (M)"
-				}
-				if row[2] == "District Hospital" {
-					row[2] = "Marutharoad"
-				}
-				if row[2] == "Neduveli" {
-					row[2] = "Vembayam"
-				}
-				d := FuzzySearch(row[1], DistrictList)
-				s := FuzzySearch(row[2], GeoLSG[d.Match])
-				if s.Score < 60 || d.Score < 60 {
+				d := MatchDistrict(row[1])
+				s := MatchLSG(d.Match, row[2], today)
+				if s.Score < REVIEW_THRESHOLD || d.Score < REVIEW_THRESHOLD {
 					log.Printf("found innaccurrate matching for %v:%v %v:%v\n", row[1], d.Match, row[2], s.Match)
+					Reviews.Add(d.Match, row[2], DASHBOARD_SOURCE, today)
+				}
+				if s.Match != "" {
+					b.Hotspots = append(b.Hotspots, NewHotspots(d.Match, s.Match, row[3], DASHBOARD_SOURCE, today))
 				}
-				b.Hotspots = append(b.Hotspots, Hotspots{District: d.Match, LSGD: s.Match, Wards: row[3]})
 			}
 			row = nil
 		})
@@ -271,3 +546,28 @@ func ScrapeHotspotsHistory(today string) (HotspotsHistory, error) {
 	log.Printf("scraped latest hotspot history (%v) in %v with %v entries\n", today, time.Now().Sub(start), len(b.Hotspots))
 	return b, nil
 }
+
+// CompareHotspots returns the hotspots of a whose LSG isn't in b and those
+// of b whose LSG isn't in a.
+func CompareHotspots(a []Hotspots, b []Hotspots) ([]Hotspots, []Hotspots) {
+	key := func(h Hotspots) string { return h.District + "/" + h.LSGD }
+	inA, inB := make(map[string]bool), make(map[string]bool)
+	for _, h := range a {
+		inA[key(h)] = true
+	}
+	for _, h := range b {
+		inB[key(h)] = true
+	}
+	var onlyA, onlyB []Hotspots
+	for _, h := range a {
+		if !inB[key(h)] {
+			onlyA = append(onlyA, h)
+		}
+	}
+	for _, h := range b {
+		if !inA[key(h)] {
+			onlyB = append(onlyB, h)
+		}
+	}
+	return onlyA, onlyB
+}
@@ -0,0 +1,154 @@
+package scraper
+
+import (
+	"fmt"
+
+	. "scrape/common"
+)
+
+// HotspotInterval is a run of consecutive hotspot lists naming an LSG. Days
+// counts the lists, so days without a list inside the run aren't counted.
+type HotspotInterval struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Days int    `json:"days"`
+}
+
+type HotspotTimeline struct {
+	District      string            `json:"district"`
+	LSGD          string            `json:"lsgd"`
+	LSGID         string            `json:"lsg_id,omitempty"`
+	Intervals     []HotspotInterval `json:"intervals"`
+	TotalDays     int               `json:"total_days"`
+	CurrentStreak int               `json:"current_streak"`
+	FirstSeen     string            `json:"first_seen,omitempty"`
+	LastSeen      string            `json:"last_seen,omitempty"`
+}
+
+// Timelines holds the hotspot timeline of every LSG built from the hotspot
+// lists up to and including Through. Previous is the date of the list
+// before it.
+type Timelines struct {
+	Timelines []HotspotTimeline `json:"timelines"`
+	Through   string            `json:"through"`
+	Previous  string            `json:"previous,omitempty"`
+}
+
+// NewTimelines returns an empty timeline for every LSG of every district.
+func NewTimelines() Timelines {
+	t := Timelines{Timelines: make([]HotspotTimeline, 0)}
+	for _, d := range DistrictList {
+		for _, lsg := range GeoLSG[d] {
+			t.Timelines = append(t.Timelines, HotspotTimeline{District: d, LSGD: lsg, LSGID: LSGGazetteer.LSGID(d, lsg, ""), Intervals: make([]HotspotInterval, 0)})
+		}
+	}
+	return t
+}
+
+// BuildTimelines computes the timelines from the whole hotspot history,
+// which must be sorted by date.
+func BuildTimelines(h []HotspotsHistory) (Timelines, error) {
+	t := NewTimelines()
+	for _, v := range h {
+		if err := t.Add(v); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+// Key identifies the timeline by the LSG's ID, or by its name when it has
+// none, so versions of an LSG sharing a name have timelines of their own.
+func (tl HotspotTimeline) Key() string {
+	if tl.LSGID != "" {
+		return tl.LSGID
+	}
+	return tl.District + "/" + tl.LSGD
+}
+
+// Add extends the timelines with the hotspot list of the day after Through.
+// An LSG listed on consecutive lists stays in one interval even when days
+// without a list lie between them, but only the listed days are counted.
+// Hotspots are resolved to the LSGs valid on the date of the list.
+func (t *Timelines) Add(h HotspotsHistory) error {
+	if t.Through != "" && !DateBefore(t.Through, h.Date) {
+		return fmt.Errorf("hotspot list of %v is not after %v", h.Date, t.Through)
+	}
+	index := make(map[string]int)
+	for i, v := range t.Timelines {
+		if v.LSGID == "" {
+			t.Timelines[i].LSGID = LSGGazetteer.LSGID(v.District, v.LSGD, v.LastSeen)
+		}
+		index[t.Timelines[i].Key()] = i
+	}
+	listed := make(map[int]bool)
+	for _, v := range h.Hotspots {
+		tl := HotspotTimeline{District: v.District, LSGD: v.LSGD, LSGID: LSGGazetteer.LSGID(v.District, v.LSGD, h.Date), Intervals: make([]HotspotInterval, 0)}
+		i, ok := index[tl.Key()]
+		if !ok {
+			i = len(t.Timelines)
+			index[tl.Key()] = i
+			t.Timelines = append(t.Timelines, tl)
+		}
+		listed[i] = true
+	}
+	for i := range t.Timelines {
+		tl := &t.Timelines[i]
+		n := len(tl.Intervals)
+		if listed[i] {
+			if n > 0 && tl.Intervals[n-1].To == t.Through {
+				tl.Intervals[n-1].To = h.Date
+				tl.Intervals[n-1].Days++
+			} else {
+				tl.Intervals = append(tl.Intervals, HotspotInterval{From: h.Date, To: h.Date, Days: 1})
+			}
+		}
+		tl.update(h.Date)
+	}
+	t.Previous, t.Through = t.Through, h.Date
+	return nil
+}
+
+// Replace swaps the list of Through for h, a newer version of the same
+// day's list, undoing only that day's part of the timelines.
+func (t *Timelines) Replace(h HotspotsHistory) error {
+	if h.Date != t.Through {
+		return fmt.Errorf("hotspot list of %v doesn't replace that of %v", h.Date, t.Through)
+	}
+	for i := range t.Timelines {
+		tl := &t.Timelines[i]
+		n := len(tl.Intervals)
+		if n == 0 || tl.Intervals[n-1].To != t.Through {
+			continue
+		}
+		if tl.Intervals[n-1].Days <= 1 {
+			tl.Intervals = tl.Intervals[:n-1]
+		} else if t.Previous == "" {
+			return fmt.Errorf("no list before %v to undo %v back to", t.Through, tl.LSGD)
+		} else {
+			tl.Intervals[n-1].To = t.Previous
+			tl.Intervals[n-1].Days--
+		}
+		tl.update(t.Previous)
+	}
+	t.Through = t.Previous
+	return t.Add(h)
+}
+
+// update recomputes the totals as of the date.
+func (tl *HotspotTimeline) update(through string) {
+	tl.TotalDays, tl.CurrentStreak = 0, 0
+	tl.FirstSeen, tl.LastSeen = "", ""
+	for _, v := range tl.Intervals {
+		tl.TotalDays += v.Days
+	}
+	n := len(tl.Intervals)
+	if n == 0 {
+		return
+	}
+	tl.FirstSeen = tl.Intervals[0].From
+	tl.LastSeen = tl.Intervals[n-1].To
+	if tl.LastSeen == through {
+		tl.CurrentStreak = tl.Intervals[n-1].Days
+	}
+}
@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+// listOf returns the hotspot list of the date naming the LSGs of Kollam.
+func listOf(date string, lsgs ...string) HotspotsHistory {
+	h := HotspotsHistory{Hotspots: make([]Hotspots, 0), Date: date}
+	for _, lsg := range lsgs {
+		h.Hotspots = append(h.Hotspots, Hotspots{District: "Kollam", LSGD: lsg})
+	}
+	return h
+}
+
+// intervalsOf returns the intervals of every LSG ever listed.
+func intervalsOf(t Timelines) map[string][]HotspotInterval {
+	out := make(map[string][]HotspotInterval)
+	for _, tl := range t.Timelines {
+		if len(tl.Intervals) > 0 {
+			out[tl.LSGD] = tl.Intervals
+		}
+	}
+	return out
+}
+
+func TestBuildTimelines(t *testing.T) {
+	// no list on 03-05-2020
+	h := []HotspotsHistory{
+		listOf("01-05-2020", "Chavara"),
+		listOf("02-05-2020", "Chavara", "Punalur"),
+		listOf("04-05-2020", "Chavara"),
+		listOf("05-05-2020", "Punalur"),
+	}
+	tl, err := BuildTimelines(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]HotspotInterval{
+		"Chavara": {{"01-05-2020", "04-05-2020", 3}},
+		"Punalur": {{"02-05-2020", "02-05-2020", 1}, {"05-05-2020", "05-05-2020", 1}},
+	}
+	if got := intervalsOf(tl); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, v := range tl.Timelines {
+		if v.LSGD == "Chavara" && (v.TotalDays != 3 || v.CurrentStreak != 0 || v.LastSeen != "04-05-2020") {
+			t.Errorf("Chavara: got %v days, streak %v, last seen %v", v.TotalDays, v.CurrentStreak, v.LastSeen)
+		}
+	}
+	if tl.Through != "05-05-2020" || tl.Previous != "04-05-2020" {
+		t.Errorf("got through %v after %v", tl.Through, tl.Previous)
+	}
+	if err := tl.Add(listOf("05-05-2020")); err == nil {
+		t.Errorf("added the list of 05-05-2020 twice")
+	}
+}
+
+func TestReplaceTimelines(t *testing.T) {
+	h := []HotspotsHistory{
+		listOf("01-05-2020", "Chavara"),
+		listOf("02-05-2020", "Chavara", "Punalur"),
+	}
+	tests := []struct {
+		name string
+		h    HotspotsHistory
+		want map[string][]HotspotInterval
+	}{
+		{"same list", listOf("02-05-2020", "Chavara", "Punalur"), map[string][]HotspotInterval{
+			"Chavara": {{"01-05-2020", "02-05-2020", 2}},
+			"Punalur": {{"02-05-2020", "02-05-2020", 1}},
+		}},
+		{"dropped", listOf("02-05-2020", "Punalur"), map[string][]HotspotInterval{
+			"Chavara": {{"01-05-2020", "01-05-2020", 1}},
+			"Punalur": {{"02-05-2020", "02-05-2020", 1}},
+		}},
+		{"changed", listOf("02-05-2020", "Chavara", "Kundara"), map[string][]HotspotInterval{
+			"Chavara": {{"01-05-2020", "02-05-2020", 2}},
+			"Kundara": {{"02-05-2020", "02-05-2020", 1}},
+		}},
+	}
+	for _, tt := range tests {
+		tl, err := BuildTimelines(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tl.Replace(tt.h); err != nil {
+			t.Fatal(err)
+		}
+		if got := intervalsOf(tl); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+		if tl.Through != "02-05-2020" || tl.Previous != "01-05-2020" {
+			t.Errorf("%v: got through %v after %v", tt.name, tl.Through, tl.Previous)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"scrape/geo"
+	"scrape/scraper"
+)
+
+// hotspotLookup answers point lookups against the boundary index and the
+// latest hotspot list, rereading the list whenever its file changes.
+type hotspotLookup struct {
+	index   *geo.Index
+	mu      sync.Mutex
+	latest  scraper.HotspotsHistory
+	modTime time.Time
+}
+
+// hotspots returns the latest hotspot list.
+func (l *hotspotLookup) hotspots() (scraper.HotspotsHistory, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fi, err := os.Stat(HOTSPOT_HISTORIES_FILE)
+	if err != nil {
+		return l.latest, err
+	}
+	if fi.ModTime().Equal(l.modTime) {
+		return l.latest, nil
+	}
+	s, err := ioutil.ReadFile(HOTSPOT_HISTORIES_FILE)
+	if err != nil {
+		return l.latest, err
+	}
+	var hh HotspotsHistories
+	if err := json.Unmarshal(s, &hh); err != nil {
+		return l.latest, err
+	}
+	if n := len(hh.History); n > 0 {
+		l.latest = hh.History[n-1]
+	}
+	l.modTime = fi.ModTime()
+	return l.latest, nil
+}
+
+// ServeHTTP answers GET /hotspot?lat=..&lon=.. with the location of the
+// point as JSON.
+func (l *hotspotLookup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lat, err1 := strconv.ParseFloat(r.URL.Query().Get("lat"), 64)
+	lon, err2 := strconv.ParseFloat(r.URL.Query().Get("lon"), 64)
+	if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		http.Error(w, "lat and lon must be valid coordinates", http.StatusBadRequest)
+		return
+	}
+	hh, err := l.hotspots()
+	if err != nil {
+		log.Println("ERROR reading hotspot list", err)
+		if hh.Date == "" {
+			http.Error(w, "hotspot list unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l.index.Lookup(lat, lon, hh))
+}
+
+// serveCommand serves point-in-hotspot lookups over HTTP from the local
+// boundary file and the latest hotspot list.
+func serveCommand(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	boundaries := fs.String("boundaries", LSG_BOUNDARIES_FILE, "GeoJSON file of LSG and ward boundaries")
+	fs.Parse(args)
+	b, err := geo.ReadBoundaries(*boundaries)
+	if err != nil {
+		log.Panicln("ERROR reading LSG boundaries", err)
+	}
+	lookup := &hotspotLookup{index: geo.NewIndex(b)}
+	if _, err := lookup.hotspots(); err != nil {
+		log.Panicln("ERROR reading hotspot list", err)
+	}
+	http.Handle("/hotspot", lookup)
+	log.Printf("serving lookups on %v for %v LSGs with boundaries and %v with ward boundaries\n", *addr, len(b.LSGs), len(b.Wards))
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+
+	. "scrape/common"
+	"scrape/scraper"
+)
+
+type historyIssue struct {
+	Date    string
+	Message string
+}
+
+// checkHistories validates the ordering of the history entries and recomputes
+// every delta from consecutive summaries, returning the problems found.
+func checkHistories(h []scraper.History) []historyIssue {
+	var issues []historyIssue
+	seen := make(map[string]bool)
+	for i, cur := range h {
+		t, err := ParseDate(cur.Date)
+		if err != nil {
+			issues = append(issues, historyIssue{cur.Date, "unparseable date"})
+			continue
+		}
+		if seen[cur.Date] {
+			issues = append(issues, historyIssue{cur.Date, "duplicate date"})
+		}
+		seen[cur.Date] = true
+		if i == 0 {
+			continue
+		}
+		prev := h[i-1]
+		p, err := ParseDate(prev.Date)
+		if err != nil {
+			continue
+		}
+		days := DaysBetween(p, t)
+		if days < 0 {
+			issues = append(issues, historyIssue{cur.Date, fmt.Sprintf("out of order, follows %v", prev.Date)})
+		} else if days > 1 {
+			issues = append(issues, historyIssue{cur.Date, fmt.Sprintf("gap of %v days after %v", days-1, prev.Date)})
+		}
+		span := 0
+		if !cur.Missing {
+			if n := scraper.DeltaSpan(h[:i], cur.Date); n > 1 {
+				span = n
+			}
+		}
+		if cur.DeltaDays != span {
+			issues = append(issues, historyIssue{cur.Date, fmt.Sprintf("delta spans %v days but is marked as %v", span, cur.DeltaDays)})
+		}
+		for d, want := range scraper.ComputeDelta(cur.Summary, prev.Summary) {
+			if got := cur.Delta[d]; got != want {
+				issues = append(issues, historyIssue{cur.Date, fmt.Sprintf("delta mismatch for %v: stored %+v, computed %+v", d, got, want)})
+			}
+		}
+		want := scraper.ComputeUnassignedDelta(cur.Unassigned, prev.Unassigned)
+		if (want == nil) != (cur.UnassignedDelta == nil) || (want != nil && *want != *cur.UnassignedDelta) {
+			issues = append(issues, historyIssue{cur.Date, fmt.Sprintf("unassigned delta mismatch: stored %+v, computed %+v", cur.UnassignedDelta, want)})
+		}
+	}
+	return issues
+}
+
+// repairHistories sorts the entries by date, keeps the last entry of any
+// duplicated date, inserts placeholders for missing days and recomputes every
+// delta after the first.
+func repairHistories(h []scraper.History) []scraper.History {
+	index := make(map[string]int)
+	var out []scraper.History
+	for _, cur := range h {
+		if _, err := ParseDate(cur.Date); err != nil {
+			log.Printf("dropping history with unparseable date %q\n", cur.Date)
+			continue
+		}
+		if i, ok := index[cur.Date]; ok {
+			out[i] = cur
+			continue
+		}
+		index[cur.Date] = len(out)
+		out = append(out, cur)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return DateBefore(out[i].Date, out[j].Date)
+	})
+	return scraper.FillGaps(out)
+}
+
+func verifyCommand(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "rewrite the histories file with gaps filled and deltas recomputed")
+	fs.Parse(args)
+
+	var histories Histories
+	ReadJSON(HISTORIES_FILE, &histories)
+	issues := checkHistories(histories.History)
+	for _, i := range issues {
+		log.Printf("%v: %v\n", i.Date, i.Message)
+	}
+	log.Printf("verified %v histories, found %v issues\n", len(histories.History), len(issues))
+	if *repair && len(issues) > 0 {
+		histories.History = repairHistories(histories.History)
+		WriteJSON(histories, HISTORIES_FILE)
+		log.Println("histories repaired")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"scrape/scraper"
+)
+
+// day returns the history of a date with Kollam at confirmed cases and the
+// delta stored as delta.
+func day(date string, confirmed int, delta int) scraper.History {
+	return scraper.History{
+		Summary: map[string]scraper.DistrictInfo{"Kollam": {Confirmed: confirmed}},
+		Delta:   map[string]scraper.DistrictInfo{"Kollam": {Confirmed: delta}},
+		Date:    date,
+	}
+}
+
+func TestCheckHistories(t *testing.T) {
+	spanned := day("04-05-2020", 16, 6)
+	spanned.DeltaDays = 2
+	tests := []struct {
+		name string
+		h    []scraper.History
+		want []historyIssue
+	}{
+		{"consistent", []scraper.History{day("01-05-2020", 10, 0), day("02-05-2020", 12, 2), day("03-05-2020", 12, 0)}, nil},
+		{"delta mismatch", []scraper.History{day("01-05-2020", 10, 0), day("02-05-2020", 12, 3)}, []historyIssue{
+			{"02-05-2020", "delta mismatch for Kollam: stored {HospitalObservation:0 HomeObservation:0 TotalObservation:0 HospitalizedToday:0 Confirmed:3 Recovered:0 Deceased:0 Active:0}, computed {HospitalObservation:0 HomeObservation:0 TotalObservation:0 HospitalizedToday:0 Confirmed:2 Recovered:0 Deceased:0 Active:0}"},
+		}},
+		{"duplicate", []scraper.History{day("01-05-2020", 10, 0), day("01-05-2020", 10, 0)}, []historyIssue{
+			{"01-05-2020", "duplicate date"},
+		}},
+		{"gap", []scraper.History{day("01-05-2020", 10, 0), day("03-05-2020", 14, 4)}, []historyIssue{
+			{"03-05-2020", "gap of 1 days after 01-05-2020"},
+			{"03-05-2020", "delta spans 2 days but is marked as 0"},
+		}},
+		{"gap marked", []scraper.History{day("02-05-2020", 10, 0), spanned}, []historyIssue{
+			{"04-05-2020", "gap of 1 days after 02-05-2020"},
+		}},
+		{"out of order", []scraper.History{day("02-05-2020", 10, 0), day("01-05-2020", 10, 0)}, []historyIssue{
+			{"01-05-2020", "out of order, follows 02-05-2020"},
+		}},
+		{"unparseable", []scraper.History{day("01-05-2020", 10, 0), day("2020-05-02", 10, 0)}, []historyIssue{
+			{"2020-05-02", "unparseable date"},
+		}},
+	}
+	for _, tt := range tests {
+		if got := checkHistories(tt.h); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}